@@ -0,0 +1,176 @@
+package efgh
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Client sends CloudEvents to a single HTTP target.
+type Client struct {
+	target     string
+	httpClient *http.Client
+	source     string
+	version    string
+	structured bool
+}
+
+// ClientOption configures a Client returned by NewClient.
+type ClientOption func(*Client)
+
+// WithSource sets the default "source" attribute for events built by
+// (*Client).NewEvent, used when the event doesn't override it with
+// WithEventSource.
+func WithSource(source string) ClientOption {
+	return func(c *Client) { c.source = source }
+}
+
+// WithHTTPClient overrides the http.Client used to send events; the default
+// is http.DefaultClient.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithStructuredMode causes the client to send events in Structured Content
+// Mode instead of the default Binary Content Mode.
+func WithStructuredMode() ClientOption {
+	return func(c *Client) { c.structured = true }
+}
+
+// NewClient returns a Client that POSTs CloudEvents to target, in CloudEvents
+// v1.0 wire format by default.
+func NewClient(target string, opts ...ClientOption) *Client {
+	c := &Client{
+		target:     target,
+		httpClient: http.DefaultClient,
+		version:    v1,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Event is a fully-built outgoing CloudEvent. Build one with
+// (*Client).NewEvent and reuse it across multiple Send calls so that every
+// subscriber receives an identical payload and ID.
+type Event struct {
+	Context CloudEventContext
+	Data    []byte
+}
+
+// EventOption overrides a default set by (*Client).NewEvent.
+type EventOption func(*CloudEventContext)
+
+// WithEventSource overrides the client's default source for a single event.
+func WithEventSource(source string) EventOption {
+	return func(cex *CloudEventContext) { cex.Source = source }
+}
+
+// WithEventID overrides the auto-generated event ID.
+func WithEventID(id string) EventOption {
+	return func(cex *CloudEventContext) { cex.EventID = id }
+}
+
+// WithEventSubject sets the event's subject attribute.
+func WithEventSubject(subject string) EventOption {
+	return func(cex *CloudEventContext) { cex.Subject = subject }
+}
+
+// NewEvent builds an Event of the given type carrying data, which is
+// marshaled to JSON unless already a []byte. The ID defaults to a new
+// random UUID, the time to now, and the source to the client's WithSource
+// option; any of these can be overridden with an EventOption.
+func (c *Client) NewEvent(eventType string, data interface{}, opts ...EventOption) (Event, error) {
+	body, contentType, err := marshalData(data)
+	if err != nil {
+		return Event{}, err
+	}
+	cex := CloudEventContext{
+		EventType:          eventType,
+		CloudEventsVersion: c.version,
+		Source:             c.source,
+		EventID:            newEventID(),
+		EventTime:          time.Now(),
+		ContentType:        contentType,
+	}
+	for _, opt := range opts {
+		opt(&cex)
+	}
+	return Event{Context: cex, Data: body}, nil
+}
+
+// marshalData marshals data to JSON unless it is already a []byte, in which
+// case it is used as-is with an unspecified content type.
+func marshalData(data interface{}) ([]byte, string, error) {
+	if b, ok := data.([]byte); ok {
+		return b, "", nil
+	}
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, "application/json", nil
+}
+
+// newEventID returns a random UUID (v4) string for use as a CloudEvents ID.
+func newEventID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// Send POSTs event to the client's target, in Binary or Structured Content
+// Mode depending on the WithStructuredMode option. Send does not modify
+// event, so the same Event can be sent to multiple clients, or resent,
+// without changing its ID or payload.
+func (c *Client) Send(ctx context.Context, event Event) error {
+	var body []byte
+	var contentType string
+	var err error
+	var binaryHeaders bool
+
+	cex := injectTraceContext(ctx, event.Context)
+
+	if c.structured {
+		if body, err = encodeStructuredMessage(cex, event.Data, c.version); err != nil {
+			return err
+		}
+		contentType = "application/cloudevents+json"
+	} else {
+		body = event.Data
+		contentType = cex.ContentType
+		binaryHeaders = true
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if binaryHeaders {
+		for k, v := range encodeBinaryHeaders(cex, c.version) {
+			req.Header.Set(k, v)
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("efgh: send to %s failed with status %s", c.target, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package efgh
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var uuidRE = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewEventDefaults(t *testing.T) {
+	c := NewClient("http://example.invalid", WithSource("/widgets"))
+
+	before := time.Now()
+	event, err := c.NewEvent("com.example.created", []byte(`{"hello":"world"}`))
+	if err != nil {
+		t.Fatalf("NewEvent: %v", err)
+	}
+
+	if !uuidRE.MatchString(event.Context.EventID) {
+		t.Errorf("EventID = %q, want a v4 UUID", event.Context.EventID)
+	}
+	if event.Context.EventTime.Before(before) || event.Context.EventTime.After(time.Now()) {
+		t.Errorf("EventTime = %v, want roughly now", event.Context.EventTime)
+	}
+	if event.Context.Source != "/widgets" {
+		t.Errorf("Source = %q, want %q", event.Context.Source, "/widgets")
+	}
+	if event.Context.ContentType != "" {
+		t.Errorf("ContentType = %q, want empty for []byte payload", event.Context.ContentType)
+	}
+	if string(event.Data) != `{"hello":"world"}` {
+		t.Errorf("Data = %s, want raw []byte passed through unchanged", event.Data)
+	}
+}
+
+func TestNewEventMarshalsStructPayloads(t *testing.T) {
+	c := NewClient("http://example.invalid")
+
+	type payload struct {
+		Name string `json:"name"`
+	}
+	event, err := c.NewEvent("com.example.created", payload{Name: "widget"})
+	if err != nil {
+		t.Fatalf("NewEvent: %v", err)
+	}
+
+	if event.Context.ContentType != "application/json" {
+		t.Errorf("ContentType = %q, want %q", event.Context.ContentType, "application/json")
+	}
+	if string(event.Data) != `{"name":"widget"}` {
+		t.Errorf("Data = %s, want %s", event.Data, `{"name":"widget"}`)
+	}
+}
+
+func TestNewEventIDsAreUnique(t *testing.T) {
+	c := NewClient("http://example.invalid")
+	a, err := c.NewEvent("com.example.created", []byte("a"))
+	if err != nil {
+		t.Fatalf("NewEvent: %v", err)
+	}
+	b, err := c.NewEvent("com.example.created", []byte("b"))
+	if err != nil {
+		t.Fatalf("NewEvent: %v", err)
+	}
+	if a.Context.EventID == b.Context.EventID {
+		t.Error("expected distinct auto-generated EventIDs")
+	}
+}
+
+func TestSendBinaryMode(t *testing.T) {
+	var gotHeaders http.Header
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		gotHeaders = req.Header.Clone()
+		gotBody, _ = io.ReadAll(req.Body)
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, WithSource("/widgets"))
+	event, err := c.NewEvent("com.example.created", []byte(`{"hello":"world"}`))
+	if err != nil {
+		t.Fatalf("NewEvent: %v", err)
+	}
+
+	if err := c.Send(context.Background(), event); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	cex, err := decodeBinaryMessage(httpHeaderMap(gotHeaders))
+	if err != nil {
+		t.Fatalf("decodeBinaryMessage: %v", err)
+	}
+	if cex.EventID != event.Context.EventID || cex.Source != "/widgets" || cex.EventType != "com.example.created" {
+		t.Errorf("decoded context = %+v, want core fields of %+v", cex, event.Context)
+	}
+	if string(gotBody) != `{"hello":"world"}` {
+		t.Errorf("body = %s, want %s", gotBody, `{"hello":"world"}`)
+	}
+}
+
+func TestSendStructuredMode(t *testing.T) {
+	var gotContentType string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		gotContentType = req.Header.Get("Content-Type")
+		gotBody, _ = io.ReadAll(req.Body)
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, WithSource("/widgets"), WithStructuredMode())
+	event, err := c.NewEvent("com.example.created", []byte(`{"hello":"world"}`))
+	if err != nil {
+		t.Fatalf("NewEvent: %v", err)
+	}
+
+	if err := c.Send(context.Background(), event); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if gotContentType != "application/cloudevents+json" {
+		t.Errorf("Content-Type = %q, want %q", gotContentType, "application/cloudevents+json")
+	}
+	cex, data, err := decodeStructuredMessage(gotBody)
+	if err != nil {
+		t.Fatalf("decodeStructuredMessage: %v", err)
+	}
+	if cex.EventID != event.Context.EventID || cex.Source != "/widgets" {
+		t.Errorf("decoded context = %+v, want core fields of %+v", cex, event.Context)
+	}
+	if string(data) != `{"hello":"world"}` {
+		t.Errorf("data = %s, want %s", data, `{"hello":"world"}`)
+	}
+}
+
+func TestSendNon2xxReturnsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	event, err := c.NewEvent("com.example.created", []byte("x"))
+	if err != nil {
+		t.Fatalf("NewEvent: %v", err)
+	}
+
+	if err := c.Send(context.Background(), event); err == nil {
+		t.Error("expected an error for a non-2xx response")
+	}
+}
@@ -0,0 +1,440 @@
+package efgh
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Per-version codecs for transcoding between the wire representation of a
+// CloudEvent (v0.1, v0.3, or v1.0, in either binary or structured content
+// mode) and the version-agnostic in-memory CloudEventContext.
+
+// Supported CloudEvents specification versions.
+const (
+	v01 = "0.1"
+	v03 = "0.3"
+	v1  = "1.0"
+)
+
+// wireAttrs names the attributes that differ between the v0.3 and v1.0 wire
+// formats; both otherwise share the same lowercase, flattened-extension
+// shape.
+type wireAttrs struct {
+	version string // value of the specversion/ce-specversion attribute
+	schema  string // "schemaurl" (v0.3) or "dataschema" (v1.0)
+}
+
+var (
+	attrsV03 = wireAttrs{version: v03, schema: "schemaurl"}
+	attrsV1  = wireAttrs{version: v1, schema: "dataschema"}
+)
+
+// specVersionFromHeaders returns the negotiated CloudEvents version for a
+// binary-mode message, given its transport headers (lowercased keys),
+// defaulting to v0.1 if no version header is set.
+func specVersionFromHeaders(headers map[string]string) string {
+	if v := headers["ce-specversion"]; v != "" {
+		return v
+	}
+	if v := headers["ce-cloudeventsversion"]; v != "" {
+		return v
+	}
+	return v01
+}
+
+// specVersionEnvelope is used to sniff the CloudEvents version out of a
+// structured-mode JSON body before picking the rest of the codec.
+type specVersionEnvelope struct {
+	SpecVersion        string `json:"specversion"`
+	CloudEventsVersion string `json:"cloudEventsVersion"`
+}
+
+func specVersionFromBody(body []byte) string {
+	var v specVersionEnvelope
+	json.Unmarshal(body, &v)
+	if v.SpecVersion != "" {
+		return v.SpecVersion
+	}
+	if v.CloudEventsVersion != "" {
+		return v.CloudEventsVersion
+	}
+	return v01
+}
+
+// isJSONContentType reports whether contentType implies data is encoded as
+// a JSON value already, rather than needing base64 encoding.
+func isJSONContentType(contentType string) bool {
+	return contentType == "" || strings.Contains(contentType, "json")
+}
+
+// wireV01 is the Structured Content Mode JSON envelope for CloudEvents v0.1
+// (https://github.com/cloudevents/spec/blob/v0.1/http-transport-binding.md#32-structured-content-mode).
+type wireV01 struct {
+	EventType          string                     `json:"eventType"`
+	EventTypeVersion   string                     `json:"eventTypeVersion,omitempty"`
+	CloudEventsVersion string                     `json:"cloudEventsVersion"`
+	Source             string                     `json:"source"`
+	EventID            string                     `json:"eventID"`
+	EventTime          *time.Time                 `json:"eventTime,omitempty"`
+	SchemaURL          string                     `json:"schemaURL,omitempty"`
+	ContentType        string                     `json:"contentType,omitempty"`
+	Extensions         map[string]json.RawMessage `json:"extensions,omitempty"`
+	Data               json.RawMessage            `json:"data,omitempty"`
+	DataBase64         string                     `json:"data_base64,omitempty"`
+}
+
+func decodeV01(body []byte) (CloudEventContext, []byte, error) {
+	var w wireV01
+	if err := json.Unmarshal(body, &w); err != nil {
+		return CloudEventContext{}, nil, err
+	}
+	cex := CloudEventContext{
+		EventType:          w.EventType,
+		EventTypeVersion:   w.EventTypeVersion,
+		CloudEventsVersion: w.CloudEventsVersion,
+		Source:             w.Source,
+		EventID:            w.EventID,
+		SchemaURL:          w.SchemaURL,
+		ContentType:        w.ContentType,
+		Extensions:         w.Extensions,
+	}
+	if w.EventTime != nil {
+		cex.EventTime = *w.EventTime
+	}
+	if cex.CloudEventsVersion == "" {
+		cex.CloudEventsVersion = v01
+	}
+
+	var data []byte
+	if w.DataBase64 != "" || !isJSONContentType(w.ContentType) {
+		decoded, err := base64.StdEncoding.DecodeString(w.DataBase64)
+		if err != nil {
+			return CloudEventContext{}, nil, err
+		}
+		data = decoded
+	} else {
+		data = w.Data
+	}
+	return cex, data, nil
+}
+
+func encodeV01(cex CloudEventContext, data []byte) ([]byte, error) {
+	w := wireV01{
+		EventType:          cex.EventType,
+		EventTypeVersion:   cex.EventTypeVersion,
+		CloudEventsVersion: v01,
+		Source:             cex.Source,
+		EventID:            cex.EventID,
+		SchemaURL:          cex.SchemaURL,
+		ContentType:        cex.ContentType,
+		Extensions:         cex.Extensions,
+	}
+	if !cex.EventTime.IsZero() {
+		t := cex.EventTime
+		w.EventTime = &t
+	}
+	if data != nil && !isJSONContentType(cex.ContentType) {
+		w.DataBase64 = base64.StdEncoding.EncodeToString(data)
+	} else {
+		w.Data = data
+	}
+	return json.Marshal(w)
+}
+
+// cexFromV01Headers decodes the binary-mode "ce-*" headers used by
+// CloudEvents v0.1
+// (https://github.com/cloudevents/spec/blob/v0.1/http-transport-binding.md#31-binary-content-mode),
+// given a message's transport headers with lowercased keys.
+func cexFromV01Headers(headers map[string]string) (CloudEventContext, error) {
+	cex := CloudEventContext{
+		EventType:          headers["ce-eventtype"],
+		EventTypeVersion:   headers["ce-eventtypeversion"],
+		CloudEventsVersion: headers["ce-cloudeventsversion"],
+		Source:             headers["ce-source"],
+		EventID:            headers["ce-eventid"],
+		SchemaURL:          headers["ce-schemaurl"],
+		ContentType:        headers["content-type"],
+	}
+	if cex.CloudEventsVersion == "" {
+		cex.CloudEventsVersion = v01
+	}
+	if ts := headers["ce-eventtime"]; ts != "" {
+		t, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			return CloudEventContext{}, err
+		}
+		cex.EventTime = t
+	}
+
+	known := map[string]bool{
+		"ce-eventtype": true, "ce-eventtypeversion": true, "ce-cloudeventsversion": true,
+		"ce-source": true, "ce-eventid": true, "ce-schemaurl": true, "ce-eventtime": true,
+	}
+	for k, v := range headers {
+		if !strings.HasPrefix(k, "ce-") || known[k] {
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return CloudEventContext{}, err
+		}
+		if cex.Extensions == nil {
+			cex.Extensions = map[string]json.RawMessage{}
+		}
+		cex.Extensions[strings.TrimPrefix(k, "ce-")] = b
+	}
+	return cex, nil
+}
+
+// headersFromV01 returns the binary-mode "ce-*" headers for CloudEvents
+// v0.1.
+func headersFromV01(cex CloudEventContext) map[string]string {
+	headers := map[string]string{
+		"ce-eventtype":          cex.EventType,
+		"ce-cloudeventsversion": v01,
+		"ce-source":             cex.Source,
+		"ce-eventid":            cex.EventID,
+	}
+	if cex.EventTypeVersion != "" {
+		headers["ce-eventtypeversion"] = cex.EventTypeVersion
+	}
+	if cex.SchemaURL != "" {
+		headers["ce-schemaurl"] = cex.SchemaURL
+	}
+	if !cex.EventTime.IsZero() {
+		headers["ce-eventtime"] = cex.EventTime.Format(time.RFC3339Nano)
+	}
+	for k, v := range cex.Extensions {
+		var s string
+		if json.Unmarshal(v, &s) == nil {
+			headers["ce-"+k] = s
+		}
+	}
+	return headers
+}
+
+// headersFromCex returns the binary-mode "ce-*" headers shared by
+// CloudEvents v0.3 and v1.0, including any extensions as additional "ce-*"
+// headers.
+func headersFromCex(cex CloudEventContext, attrs wireAttrs) map[string]string {
+	headers := map[string]string{
+		"ce-id":          cex.EventID,
+		"ce-source":      cex.Source,
+		"ce-type":        cex.EventType,
+		"ce-specversion": attrs.version,
+	}
+	if cex.Subject != "" {
+		headers["ce-subject"] = cex.Subject
+	}
+	if cex.SchemaURL != "" {
+		headers["ce-"+attrs.schema] = cex.SchemaURL
+	}
+	if !cex.EventTime.IsZero() {
+		headers["ce-time"] = cex.EventTime.Format(time.RFC3339Nano)
+	}
+	for k, v := range cex.Extensions {
+		var s string
+		if json.Unmarshal(v, &s) == nil {
+			headers["ce-"+k] = s
+		}
+	}
+	return headers
+}
+
+// cexFromHeaders decodes the binary-mode context attribute headers shared by
+// CloudEvents v0.3 and v1.0: lowercased "ce-*" headers, with any "ce-*"
+// header not matching a known attribute carried as an extension.
+func cexFromHeaders(headers map[string]string, attrs wireAttrs) (CloudEventContext, error) {
+	cex := CloudEventContext{
+		EventID:            headers["ce-id"],
+		Source:             headers["ce-source"],
+		EventType:          headers["ce-type"],
+		CloudEventsVersion: headers["ce-specversion"],
+		Subject:            headers["ce-subject"],
+		ContentType:        headers["content-type"],
+		SchemaURL:          headers["ce-"+attrs.schema],
+	}
+	if ts := headers["ce-time"]; ts != "" {
+		t, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			return CloudEventContext{}, err
+		}
+		cex.EventTime = t
+	}
+
+	known := map[string]bool{
+		"ce-id": true, "ce-source": true, "ce-type": true, "ce-specversion": true,
+		"ce-subject": true, "ce-time": true, "ce-" + attrs.schema: true,
+	}
+	for k, v := range headers {
+		if !strings.HasPrefix(k, "ce-") || known[k] {
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return CloudEventContext{}, err
+		}
+		if cex.Extensions == nil {
+			cex.Extensions = map[string]json.RawMessage{}
+		}
+		cex.Extensions[strings.TrimPrefix(k, "ce-")] = b
+	}
+	return cex, nil
+}
+
+// knownFlattenedKeys are the top-level JSON keys of the v0.3/v1.0 structured
+// envelope that are context attributes rather than extensions.
+func knownFlattenedKeys(attrs wireAttrs) map[string]bool {
+	return map[string]bool{
+		"id": true, "source": true, "type": true, "specversion": true,
+		"subject": true, "time": true, attrs.schema: true,
+		"datacontenttype": true, "data": true, "data_base64": true,
+	}
+}
+
+// decodeFlattened decodes the structured-mode JSON envelope shared by
+// CloudEvents v0.3 and v1.0, where extensions are flattened as additional
+// top-level attributes rather than nested under "extensions".
+func decodeFlattened(body []byte, attrs wireAttrs) (CloudEventContext, []byte, error) {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(body, &m); err != nil {
+		return CloudEventContext{}, nil, err
+	}
+
+	str := func(key string) string {
+		var s string
+		if raw, ok := m[key]; ok {
+			json.Unmarshal(raw, &s)
+		}
+		return s
+	}
+
+	cex := CloudEventContext{
+		EventID:            str("id"),
+		Source:             str("source"),
+		EventType:          str("type"),
+		CloudEventsVersion: str("specversion"),
+		Subject:            str("subject"),
+		ContentType:        str("datacontenttype"),
+		SchemaURL:          str(attrs.schema),
+	}
+	if ts := str("time"); ts != "" {
+		t, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			return CloudEventContext{}, nil, err
+		}
+		cex.EventTime = t
+	}
+
+	var data []byte
+	if dataBase64 := str("data_base64"); dataBase64 != "" {
+		decoded, err := base64.StdEncoding.DecodeString(dataBase64)
+		if err != nil {
+			return CloudEventContext{}, nil, err
+		}
+		data = decoded
+	} else if raw, ok := m["data"]; ok {
+		data = raw
+	}
+
+	known := knownFlattenedKeys(attrs)
+	for k, v := range m {
+		if known[k] {
+			continue
+		}
+		if cex.Extensions == nil {
+			cex.Extensions = map[string]json.RawMessage{}
+		}
+		cex.Extensions[k] = v
+	}
+	return cex, data, nil
+}
+
+// encodeFlattened encodes cex and data into the structured-mode JSON
+// envelope shared by CloudEvents v0.3 and v1.0.
+func encodeFlattened(cex CloudEventContext, attrs wireAttrs, data []byte) ([]byte, error) {
+	m := map[string]json.RawMessage{}
+	for k, v := range cex.Extensions {
+		m[k] = v
+	}
+	set := func(key, val string) {
+		if val == "" {
+			return
+		}
+		b, _ := json.Marshal(val)
+		m[key] = b
+	}
+	set("id", cex.EventID)
+	set("source", cex.Source)
+	set("type", cex.EventType)
+	set("specversion", attrs.version)
+	set("subject", cex.Subject)
+	set(attrs.schema, cex.SchemaURL)
+	set("datacontenttype", cex.ContentType)
+	if !cex.EventTime.IsZero() {
+		set("time", cex.EventTime.Format(time.RFC3339Nano))
+	}
+	if data != nil {
+		if isJSONContentType(cex.ContentType) {
+			m["data"] = json.RawMessage(data)
+		} else {
+			set("data_base64", base64.StdEncoding.EncodeToString(data))
+		}
+	}
+	return json.Marshal(m)
+}
+
+// decodeBinaryMessage negotiates the CloudEvents version from a message's
+// transport headers (lowercased keys) and decodes its binary-mode context
+// attributes.
+func decodeBinaryMessage(headers map[string]string) (CloudEventContext, error) {
+	switch specVersionFromHeaders(headers) {
+	case v03:
+		return cexFromHeaders(headers, attrsV03)
+	case v1:
+		return cexFromHeaders(headers, attrsV1)
+	default:
+		return cexFromV01Headers(headers)
+	}
+}
+
+// encodeBinaryHeaders returns the binary-mode context attribute headers
+// (lowercased keys) for cex in the given CloudEvents version.
+func encodeBinaryHeaders(cex CloudEventContext, version string) map[string]string {
+	switch version {
+	case v03:
+		return headersFromCex(cex, attrsV03)
+	case v01:
+		return headersFromV01(cex)
+	default:
+		return headersFromCex(cex, attrsV1)
+	}
+}
+
+// decodeStructuredMessage negotiates the CloudEvents version from a
+// structured-mode JSON envelope and decodes it.
+func decodeStructuredMessage(body []byte) (CloudEventContext, []byte, error) {
+	switch specVersionFromBody(body) {
+	case v03:
+		return decodeFlattened(body, attrsV03)
+	case v1:
+		return decodeFlattened(body, attrsV1)
+	default:
+		return decodeV01(body)
+	}
+}
+
+// encodeStructuredMessage encodes cex and data as a structured-mode JSON
+// envelope in the given CloudEvents version.
+func encodeStructuredMessage(cex CloudEventContext, data []byte, version string) ([]byte, error) {
+	switch version {
+	case v03:
+		return encodeFlattened(cex, attrsV03, data)
+	case v01:
+		return encodeV01(cex, data)
+	default:
+		return encodeFlattened(cex, attrsV1, data)
+	}
+}
@@ -0,0 +1,115 @@
+package efgh
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecodeBinaryMessageVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string // expected CloudEventsVersion
+	}{
+		{"no version header defaults to v0.1", map[string]string{"ce-eventtype": "t", "ce-source": "s", "ce-eventid": "1"}, v01},
+		{"explicit v0.1 version header", map[string]string{"ce-cloudeventsversion": v01, "ce-eventtype": "t"}, v01},
+		{"v0.3", map[string]string{"ce-specversion": v03, "ce-type": "t"}, v03},
+		{"v1.0", map[string]string{"ce-specversion": v1, "ce-type": "t"}, v1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cex, err := decodeBinaryMessage(tt.headers)
+			if err != nil {
+				t.Fatalf("decodeBinaryMessage: %v", err)
+			}
+			if cex.CloudEventsVersion != tt.want {
+				t.Errorf("CloudEventsVersion = %q, want %q", cex.CloudEventsVersion, tt.want)
+			}
+		})
+	}
+}
+
+func TestCexFromV01HeadersExtensions(t *testing.T) {
+	headers := map[string]string{
+		"ce-eventtype":   "com.example.created",
+		"ce-source":      "/widgets",
+		"ce-eventid":     "1234",
+		"ce-traceparent": "00-trace-01",
+		"ce-tracestate":  "vendor=val",
+		"content-type":   "application/json",
+	}
+	cex, err := cexFromV01Headers(headers)
+	if err != nil {
+		t.Fatalf("cexFromV01Headers: %v", err)
+	}
+
+	var traceparent string
+	if raw, ok := cex.Extensions["traceparent"]; !ok {
+		t.Fatal("expected traceparent extension to be populated")
+	} else if err := json.Unmarshal(raw, &traceparent); err != nil {
+		t.Fatalf("unmarshal traceparent extension: %v", err)
+	} else if traceparent != "00-trace-01" {
+		t.Errorf("traceparent = %q, want %q", traceparent, "00-trace-01")
+	}
+
+	if _, ok := cex.Extensions["eventtype"]; ok {
+		t.Error("known attribute ce-eventtype should not be carried as an extension")
+	}
+}
+
+func TestEncodeDecodeBinaryRoundTrip(t *testing.T) {
+	for _, version := range []string{v01, v03, v1} {
+		t.Run(version, func(t *testing.T) {
+			cex := CloudEventContext{
+				EventID:            "1234",
+				Source:             "/widgets",
+				EventType:          "com.example.created",
+				CloudEventsVersion: version,
+				Extensions:         map[string]json.RawMessage{"customattr": json.RawMessage(`"hello"`)},
+			}
+			headers := encodeBinaryHeaders(cex, version)
+			got, err := decodeBinaryMessage(headers)
+			if err != nil {
+				t.Fatalf("decodeBinaryMessage: %v", err)
+			}
+			if got.EventID != cex.EventID || got.Source != cex.Source || got.EventType != cex.EventType {
+				t.Errorf("round-tripped context = %+v, want core fields of %+v", got, cex)
+			}
+
+			var custom string
+			if raw, ok := got.Extensions["customattr"]; !ok {
+				t.Fatal("expected customattr extension to round-trip")
+			} else if err := json.Unmarshal(raw, &custom); err != nil || custom != "hello" {
+				t.Errorf("customattr extension = %q, err %v, want %q", custom, err, "hello")
+			}
+		})
+	}
+}
+
+func TestEncodeDecodeStructuredRoundTrip(t *testing.T) {
+	for _, version := range []string{v01, v03, v1} {
+		t.Run(version, func(t *testing.T) {
+			cex := CloudEventContext{
+				EventID:            "1234",
+				Source:             "/widgets",
+				EventType:          "com.example.created",
+				CloudEventsVersion: version,
+			}
+			data := []byte(`{"hello":"world"}`)
+			body, err := encodeStructuredMessage(cex, data, version)
+			if err != nil {
+				t.Fatalf("encodeStructuredMessage: %v", err)
+			}
+			got, gotData, err := decodeStructuredMessage(body)
+			if err != nil {
+				t.Fatalf("decodeStructuredMessage: %v", err)
+			}
+			if got.EventID != cex.EventID || got.Source != cex.Source || got.EventType != cex.EventType {
+				t.Errorf("round-tripped context = %+v, want core fields of %+v", got, cex)
+			}
+			if string(gotData) != string(data) {
+				t.Errorf("round-tripped data = %s, want %s", gotData, data)
+			}
+		})
+	}
+}
@@ -3,69 +3,36 @@ package efgh
 import (
 	"context"
 	"encoding/json"
-	"log"
-	"net/http"
-	"os"
 	"time"
 )
 
-// Start instantiates a webserver to surface the supplied function
-// as an HTTP endpoint implementing the
-// [Cloud Events HTTP transport](https://github.com/cloudevents/spec/blob/v0.1/http-transport-binding.md#31-binary-content-mode).
-//
-// Start takes a flexible range of function signatures; any combination
-// of the following should work for input args:
-//
-// ```
-// func DoIt()
-// func DoIt(context.Context)
-// func DoIt([]bytes)
-// func DoIt(interface{})  // For JSON unmarshal
-// func DoIt(context.Context, [] bytes)
-// func DoIt(context.Context, interface{})  // For JSON unmarshal
-// ```
-//
-// Similarly, the return value of the function may be any of:
-//
-// ```
-// func DoIt()
-// func DoIt() error
-// func DoIt() []byte
-// func DoIt() interface{}  // For JSON marshall
-// func DoIt() (error, []byte)
-// func DoIt() (error, interface{})  // For JSON marshall
-// ```
-func Start(function interface{}) {
-	handler, err := wrap(function)
-	if err != nil {
-		log.Fatal(err)
-	}
-	http.Handle("/", handler)
-	port := ":" + os.Getenv("PORT")
-	log.Printf("Listening on %s\n", port)
-	log.Fatal(http.ListenAndServe(port, nil))
-}
-
-// CloudEventContext represents the CloudEvents "context" fields.
+// CloudEventContext represents the CloudEvents "context" fields, normalized
+// to a single in-memory shape regardless of which wire version (v0.1, v0.3,
+// or v1.0) produced or will consume it; see the codecs in codec.go for the
+// per-version wire mappings.
 type CloudEventContext struct {
 	// Type of occurence which has happened.
-	EventType string `json:"eventType"`
+	EventType string
 	// The version of the `eventType`; this is producer-specific.
-	EventTypeVersion string `json:"eventTypeVersion,omitempty"`
-	// The version of the CloudEVents specification used by the event.
-	CloudEventsVersion string `json:"cloudEventsVersion"`
+	EventTypeVersion string
+	// The version of the CloudEvents specification used on the wire:
+	// "0.1", "0.3", or "1.0".
+	CloudEventsVersion string
 	// The event producer; this is a URI, but exact syntax is producer-specific.
-	Source string `json:"source"`
+	Source string
 	// ID of the event; must be non-empty and unique within the scope of the producer.
-	EventID string `json:"eventID"`
+	EventID string
+	// Describes the subject of the event in the context of the event
+	// producer. Carried by CloudEvents v0.3 and v1.0; empty for v0.1 events.
+	Subject string
 	// Timestamp of when the event happened.
-	EventTime time.Time `json:"eventTime,omitempty"`
+	EventTime time.Time
 	// A link to the schema that the `data` attribute adheres to.
-	SchemaURL string `json:"schemaURL,omitempty"`
+	SchemaURL string
 	// Describes the data encoding format.
-	ContentType string `json:"contentType,omitempty"`
+	ContentType string
 	// Additional metadata without a well-defined structure.
-	Extensions map[string]json.RawMessage `json:"extensions,omitempty"`
+	Extensions map[string]json.RawMessage
 }
 
 // CloudEvent extracts a CloudEventContext from the current context.
@@ -74,9 +41,35 @@ func CloudEvent(ctx context.Context) (CloudEventContext, bool) {
 	return r, ok
 }
 
+// SetCloudEvent overrides the CloudEventContext that will be used to build
+// the response, in place of the context carried by the incoming event. The
+// override applies in both content modes: it forms the envelope of a
+// structured-mode response, and the ce-* headers (or attributes) of a
+// binary-mode one.
+//
+// Context values are normally immutable, so this works by mutating a holder
+// placed in ctx before the user function was invoked, rather than by
+// returning a new context.Context.
+func SetCloudEvent(ctx context.Context, cex CloudEventContext) {
+	if out, ok := ctx.Value(outgoingKey).(*outgoingEvent); ok {
+		out.cex = cex
+	}
+}
+
 // key is an unexported type for keys defined in this package.
 // This prevents collisions with keys defined in other packages.
 type key int
 
-// contextKey is the key for CloudEventContext values in Contexts.
-var contextKey key
+const (
+	// contextKey is the key for the incoming CloudEventContext in Contexts.
+	contextKey key = iota
+	// outgoingKey is the key for the *outgoingEvent holder in Contexts.
+	outgoingKey
+)
+
+// outgoingEvent is a mutable holder for the CloudEventContext that will be
+// used for the response. It is stored behind a pointer so that
+// SetCloudEvent can change it from within a user function.
+type outgoingEvent struct {
+	cex CloudEventContext
+}
@@ -0,0 +1,697 @@
+package efgh
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filter is a parsed CloudEvents SQL expression
+// (https://github.com/cloudevents/spec/blob/v1.0.1/cesql/spec.md), evaluated
+// against an event's CloudEventContext to decide whether the function
+// registered with WithFilter should be invoked for it.
+type Filter struct {
+	root filterNode
+}
+
+// WithFilter parses expr as a CloudEvents SQL expression at Start time, and
+// causes Start to skip invoking the function for events it evaluates false
+// for, acknowledging them as dropped instead (an HTTP 204 No Content, or the
+// transport-appropriate equivalent) rather than as an error.
+func WithFilter(expr string) Option {
+	return func(c *config) { c.filterExpr = expr }
+}
+
+// ParseFilter parses a CloudEvents SQL expression into a reusable Filter.
+func ParseFilter(expr string) (*Filter, error) {
+	p, err := newFilterParser(expr)
+	if err != nil {
+		return nil, err
+	}
+	node, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, fmt.Errorf("efgh: unexpected trailing input in filter expression")
+	}
+	return &Filter{root: node}, nil
+}
+
+// Match reports whether cex satisfies the filter expression. A predicate
+// that can't be evaluated (e.g. comparing against a missing attribute)
+// evaluates to false on its own, before any enclosing NOT is applied, so
+// NOT of such a predicate matches; unlike SQL's three-valued logic, there is
+// no separate unknown result.
+func (f *Filter) Match(cex CloudEventContext) bool {
+	ok, err := f.root.eval(cex)
+	if err != nil {
+		return false
+	}
+	return ok
+}
+
+// filterNode is one node of a parsed filter expression's AST.
+type filterNode interface {
+	eval(cex CloudEventContext) (bool, error)
+}
+
+type andNode struct{ left, right filterNode }
+
+func (n andNode) eval(cex CloudEventContext) (bool, error) {
+	l, err := n.left.eval(cex)
+	if err != nil || !l {
+		return false, err
+	}
+	return n.right.eval(cex)
+}
+
+type orNode struct{ left, right filterNode }
+
+func (n orNode) eval(cex CloudEventContext) (bool, error) {
+	l, err := n.left.eval(cex)
+	if err != nil {
+		return false, err
+	}
+	if l {
+		return true, nil
+	}
+	return n.right.eval(cex)
+}
+
+type notNode struct{ operand filterNode }
+
+func (n notNode) eval(cex CloudEventContext) (bool, error) {
+	v, err := n.operand.eval(cex)
+	if err != nil {
+		return false, err
+	}
+	return !v, nil
+}
+
+type boolLiteralNode struct{ value bool }
+
+func (n boolLiteralNode) eval(CloudEventContext) (bool, error) { return n.value, nil }
+
+type existsNode struct{ attr string }
+
+func (n existsNode) eval(cex CloudEventContext) (bool, error) {
+	_, ok := attrValue(cex, n.attr)
+	return ok, nil
+}
+
+type compareNode struct {
+	op          string
+	left, right operand
+}
+
+func (n compareNode) eval(cex CloudEventContext) (bool, error) {
+	l, lok := resolveOperand(cex, n.left)
+	r, rok := resolveOperand(cex, n.right)
+	if !lok || !rok {
+		return false, nil
+	}
+	cmp, ok := compareValues(l, r)
+	if !ok {
+		return false, nil
+	}
+	switch n.op {
+	case "=":
+		return cmp == 0, nil
+	case "<>":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	}
+	return false, fmt.Errorf("efgh: unknown comparison operator %q", n.op)
+}
+
+type likeNode struct {
+	operand operand
+	pattern string
+}
+
+func (n likeNode) eval(cex CloudEventContext) (bool, error) {
+	v, ok := resolveOperand(cex, n.operand)
+	if !ok {
+		return false, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return false, nil
+	}
+	return matchLike(s, n.pattern), nil
+}
+
+type inNode struct {
+	operand operand
+	values  []operand
+}
+
+func (n inNode) eval(cex CloudEventContext) (bool, error) {
+	v, ok := resolveOperand(cex, n.operand)
+	if !ok {
+		return false, nil
+	}
+	for _, cand := range n.values {
+		c, ok := resolveOperand(cex, cand)
+		if !ok {
+			continue
+		}
+		if cmp, ok := compareValues(v, c); ok && cmp == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// operand is either an attrRef (resolved against an event) or a literal.
+type operand interface{}
+
+// attrRef is an unresolved reference to a context or extension attribute.
+type attrRef string
+
+// literal is a string, float64, or bool constant.
+type literal struct{ value interface{} }
+
+func resolveOperand(cex CloudEventContext, op operand) (interface{}, bool) {
+	switch o := op.(type) {
+	case attrRef:
+		return attrValue(cex, string(o))
+	case literal:
+		return o.value, true
+	default:
+		return nil, false
+	}
+}
+
+// attrValue resolves a CloudEvents SQL attribute reference against cex: the
+// well-known context attributes by name, falling back to extensions.
+func attrValue(cex CloudEventContext, name string) (interface{}, bool) {
+	switch strings.ToLower(name) {
+	case "id":
+		return cex.EventID, cex.EventID != ""
+	case "source":
+		return cex.Source, cex.Source != ""
+	case "type":
+		return cex.EventType, cex.EventType != ""
+	case "specversion":
+		return cex.CloudEventsVersion, cex.CloudEventsVersion != ""
+	case "subject":
+		return cex.Subject, cex.Subject != ""
+	case "datacontenttype":
+		return cex.ContentType, cex.ContentType != ""
+	case "dataschema", "schemaurl":
+		return cex.SchemaURL, cex.SchemaURL != ""
+	default:
+		raw, ok := cex.Extensions[name]
+		if !ok {
+			return nil, false
+		}
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			return s, true
+		}
+		var f float64
+		if json.Unmarshal(raw, &f) == nil {
+			return f, true
+		}
+		var b bool
+		if json.Unmarshal(raw, &b) == nil {
+			return b, true
+		}
+		return string(raw), true
+	}
+}
+
+// compareValues compares a and b, coercing b to a's type, and reports
+// whether they were comparable at all.
+func compareValues(a, b interface{}) (int, bool) {
+	switch av := a.(type) {
+	case string:
+		bv, ok := asString(b)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(av, bv), true
+	case float64:
+		bv, ok := asFloat(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case av < bv:
+			return -1, true
+		case av > bv:
+			return 1, true
+		default:
+			return 0, true
+		}
+	case bool:
+		bv, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		if av == bv {
+			return 0, true
+		}
+		return 1, true
+	}
+	return 0, false
+}
+
+func asString(v interface{}) (string, bool) {
+	switch s := v.(type) {
+	case string:
+		return s, true
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(s), true
+	}
+	return "", false
+}
+
+func asFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		if err != nil {
+			return 0, false
+		}
+		return f, true
+	}
+	return 0, false
+}
+
+// matchLike reports whether s matches a CloudEvents SQL LIKE pattern, where
+// "%" matches any run of characters and "_" matches exactly one.
+func matchLike(s, pattern string) bool {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			sb.WriteString(".*")
+		case '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return false
+	}
+	return re.MatchString(s)
+}
+
+// Lexer and recursive-descent parser for the grammar:
+//
+//	expr      := or
+//	or        := and (OR and)*
+//	and       := not (AND not)*
+//	not       := NOT not | primary
+//	primary   := '(' expr ')' | predicate
+//	predicate := operand ( compareOp operand
+//	                     | LIKE string
+//	                     | IN '(' operand (',' operand)* ')'
+//	                     | EXISTS
+//	                     )?
+//	operand   := IDENT | STRING | NUMBER | TRUE | FALSE
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokTrue
+	tokFalse
+	tokLParen
+	tokRParen
+	tokComma
+	tokOp
+	tokAnd
+	tokOr
+	tokNot
+	tokLike
+	tokIn
+	tokExists
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+type filterLexer struct {
+	input []rune
+	pos   int
+}
+
+func (l *filterLexer) peek() rune {
+	if l.pos >= len(l.input) {
+		return 0
+	}
+	return l.input[l.pos]
+}
+
+func (l *filterLexer) next() (token, error) {
+	for l.pos < len(l.input) && unicode.IsSpace(l.input[l.pos]) {
+		l.pos++
+	}
+	if l.pos >= len(l.input) {
+		return token{kind: tokEOF}, nil
+	}
+
+	c := l.input[l.pos]
+	switch {
+	case c == '(':
+		l.pos++
+		return token{kind: tokLParen}, nil
+	case c == ')':
+		l.pos++
+		return token{kind: tokRParen}, nil
+	case c == ',':
+		l.pos++
+		return token{kind: tokComma}, nil
+	case c == '\'':
+		return l.lexString()
+	case c == '=':
+		l.pos++
+		return token{kind: tokOp, text: "="}, nil
+	case c == '<':
+		l.pos++
+		if l.peek() == '>' {
+			l.pos++
+			return token{kind: tokOp, text: "<>"}, nil
+		}
+		if l.peek() == '=' {
+			l.pos++
+			return token{kind: tokOp, text: "<="}, nil
+		}
+		return token{kind: tokOp, text: "<"}, nil
+	case c == '>':
+		l.pos++
+		if l.peek() == '=' {
+			l.pos++
+			return token{kind: tokOp, text: ">="}, nil
+		}
+		return token{kind: tokOp, text: ">"}, nil
+	case unicode.IsDigit(c):
+		return l.lexNumber()
+	case c == '-' && l.pos+1 < len(l.input) && unicode.IsDigit(l.input[l.pos+1]):
+		return l.lexNumber()
+	case unicode.IsLetter(c) || c == '_':
+		return l.lexIdent()
+	default:
+		return token{}, fmt.Errorf("efgh: unexpected character %q in filter expression", c)
+	}
+}
+
+func (l *filterLexer) lexString() (token, error) {
+	l.pos++ // opening quote
+	var sb strings.Builder
+	for {
+		if l.pos >= len(l.input) {
+			return token{}, fmt.Errorf("efgh: unterminated string literal in filter expression")
+		}
+		c := l.input[l.pos]
+		if c == '\'' {
+			l.pos++
+			if l.peek() == '\'' { // escaped quote
+				sb.WriteRune('\'')
+				l.pos++
+				continue
+			}
+			break
+		}
+		sb.WriteRune(c)
+		l.pos++
+	}
+	return token{kind: tokString, text: sb.String()}, nil
+}
+
+func (l *filterLexer) lexNumber() (token, error) {
+	start := l.pos
+	if l.input[l.pos] == '-' {
+		l.pos++
+	}
+	for l.pos < len(l.input) && (unicode.IsDigit(l.input[l.pos]) || l.input[l.pos] == '.') {
+		l.pos++
+	}
+	return token{kind: tokNumber, text: string(l.input[start:l.pos])}, nil
+}
+
+func (l *filterLexer) lexIdent() (token, error) {
+	start := l.pos
+	for l.pos < len(l.input) && (unicode.IsLetter(l.input[l.pos]) || unicode.IsDigit(l.input[l.pos]) || l.input[l.pos] == '_' || l.input[l.pos] == '.') {
+		l.pos++
+	}
+	word := string(l.input[start:l.pos])
+	switch strings.ToUpper(word) {
+	case "AND":
+		return token{kind: tokAnd}, nil
+	case "OR":
+		return token{kind: tokOr}, nil
+	case "NOT":
+		return token{kind: tokNot}, nil
+	case "LIKE":
+		return token{kind: tokLike}, nil
+	case "IN":
+		return token{kind: tokIn}, nil
+	case "EXISTS":
+		return token{kind: tokExists}, nil
+	case "TRUE":
+		return token{kind: tokTrue}, nil
+	case "FALSE":
+		return token{kind: tokFalse}, nil
+	default:
+		return token{kind: tokIdent, text: word}, nil
+	}
+}
+
+type filterParser struct {
+	lex *filterLexer
+	tok token
+}
+
+func newFilterParser(expr string) (*filterParser, error) {
+	p := &filterParser{lex: &filterLexer{input: []rune(expr)}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *filterParser) advance() error {
+	t, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = t
+	return nil
+}
+
+func (p *filterParser) parseExpr() (filterNode, error) { return p.parseOr() }
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOr {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokAnd {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (filterNode, error) {
+	if p.tok.kind == tokNot {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (filterNode, error) {
+	if p.tok.kind == tokLParen {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, fmt.Errorf("efgh: expected ')' in filter expression")
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	}
+	return p.parsePredicate()
+}
+
+func (p *filterParser) parsePredicate() (filterNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.tok.kind {
+	case tokOp:
+		op := p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return compareNode{op: op, left: left, right: right}, nil
+	case tokLike:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokString {
+			return nil, fmt.Errorf("efgh: LIKE requires a string pattern")
+		}
+		pattern := p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return likeNode{operand: left, pattern: pattern}, nil
+	case tokIn:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokLParen {
+			return nil, fmt.Errorf("efgh: IN requires a parenthesized list")
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		var values []operand
+		for {
+			v, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+			if p.tok.kind == tokComma {
+				if err := p.advance(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			break
+		}
+		if p.tok.kind != tokRParen {
+			return nil, fmt.Errorf("efgh: expected ')' to close IN list")
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return inNode{operand: left, values: values}, nil
+	case tokExists:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		ref, ok := left.(attrRef)
+		if !ok {
+			return nil, fmt.Errorf("efgh: EXISTS requires an attribute reference")
+		}
+		return existsNode{attr: string(ref)}, nil
+	default:
+		lit, ok := left.(literal)
+		if b, bok := lit.value.(bool); ok && bok {
+			return boolLiteralNode{value: b}, nil
+		}
+		return nil, fmt.Errorf("efgh: invalid filter predicate")
+	}
+}
+
+func (p *filterParser) parseOperand() (operand, error) {
+	switch p.tok.kind {
+	case tokIdent:
+		name := p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return attrRef(name), nil
+	case tokString:
+		s := p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return literal{value: s}, nil
+	case tokNumber:
+		n, err := strconv.ParseFloat(p.tok.text, 64)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return literal{value: n}, nil
+	case tokTrue:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return literal{value: true}, nil
+	case tokFalse:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return literal{value: false}, nil
+	default:
+		return nil, fmt.Errorf("efgh: expected attribute, literal, or parenthesized expression in filter")
+	}
+}
@@ -0,0 +1,80 @@
+package efgh
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFilterMatch(t *testing.T) {
+	cex := CloudEventContext{
+		EventID:            "1234",
+		Source:             "/widgets",
+		EventType:          "com.example.widget.created",
+		CloudEventsVersion: v1,
+		Subject:            "widget-42",
+		Extensions: map[string]json.RawMessage{
+			"count": json.RawMessage(`5`),
+			"label": json.RawMessage(`"blue"`),
+		},
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		want bool
+	}{
+		{"equals match", `type = 'com.example.widget.created'`, true},
+		{"equals no match", `type = 'com.example.widget.deleted'`, false},
+		{"not equal", `type <> 'com.example.widget.deleted'`, true},
+		{"and", `type = 'com.example.widget.created' AND subject = 'widget-42'`, true},
+		{"or", `type = 'nope' OR subject = 'widget-42'`, true},
+		{"not", `NOT (type = 'nope')`, true},
+		{"like", `source LIKE '/widg%'`, true},
+		{"like no match", `source LIKE '/gadg%'`, false},
+		{"in", `subject IN ('widget-1', 'widget-42')`, true},
+		{"exists", `subject EXISTS`, true},
+		{"exists missing", `missingattr EXISTS`, false},
+		{"extension greater than", `count > 1`, true},
+		{"extension negative literal", `count > -5`, true},
+		{"extension less than negative", `count < -5`, false},
+		{"extension string", `label = 'blue'`, true},
+		{"missing attribute comparison", `missingattr = 'x'`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseFilter(%q): %v", tt.expr, err)
+			}
+			if got := f.Match(cex); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterNegativeNumber(t *testing.T) {
+	f, err := ParseFilter(`count > -5`)
+	if err != nil {
+		t.Fatalf("ParseFilter: %v", err)
+	}
+	cex := CloudEventContext{Extensions: map[string]json.RawMessage{"count": json.RawMessage(`0`)}}
+	if !f.Match(cex) {
+		t.Error("expected 0 > -5 to match")
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []string{
+		`type = `,
+		`type = 'unterminated`,
+		`(type = 'a'`,
+		`type = 'a' extra`,
+	}
+	for _, expr := range tests {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("ParseFilter(%q): expected error, got nil", expr)
+		}
+	}
+}
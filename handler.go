@@ -3,18 +3,12 @@ package efgh
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"log"
-	"net/http"
 	"reflect"
-	"strings"
-	"time"
 )
 
-// Implementation of HTTP processing for an event function.
+// Implementation of reflection-based dispatch to a user function.
 
 type functionHandler struct {
 	// The function in question
@@ -64,74 +58,27 @@ func (fh functionHandler) Invoke(ctx context.Context, in []byte) (out []byte, er
 	return
 }
 
-func (fh functionHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
-	if req.Method != http.MethodPost {
-		rw.WriteHeader(http.StatusMethodNotAllowed)
-		return
-	}
-	var in []byte
-	var ctx context.Context
-	var err error
-
-	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/cloudevents") {
-		in, ctx, err = convertStructured(req)
-	} else {
-		in, ctx, err = convertBinary(req)
-	}
-	if err != nil {
-		rw.Header().Set("Content-Type", "text/plain")
-		rw.WriteHeader(http.StatusExpectationFailed)
-		io.WriteString(rw, err.Error())
-		return
-	}
-	// Handle different function types.
-	out, err := fh.Invoke(ctx, in)
-	if err != nil {
-		rw.WriteHeader(http.StatusInternalServerError)
-		io.WriteString(rw, err.Error())
-		return
-	}
-	// TODO: extract headers and/or handle binary vs structured on response.
-	rw.WriteHeader(http.StatusOK)
-	rw.Write(out)
+// newEventContext attaches cex to parent as both the incoming
+// CloudEventContext (see CloudEvent) and the default outgoing
+// CloudEventContext for the response (see SetCloudEvent).
+func newEventContext(parent context.Context, cex CloudEventContext) context.Context {
+	ctx := context.WithValue(parent, contextKey, cex)
+	return context.WithValue(ctx, outgoingKey, &outgoingEvent{cex: cex})
 }
 
-// Handle Structed Content Mode (https://github.com/cloudevents/spec/blob/v0.1/http-transport-binding.md#32-structured-content-mode)
-func convertStructured(req *http.Request) ([]byte, context.Context, error) {
-	return nil, nil, errors.New("structured output not supported yet")
-}
-
-// Handle Binary Content Mode (https://github.com/cloudevents/spec/blob/v0.1/http-transport-binding.md#31-binary-content-mode)
-func convertBinary(req *http.Request) ([]byte, context.Context, error) {
-	cex := CloudEventContext{
-		EventType:          req.Header.Get("CE-EventType"),
-		EventTypeVersion:   req.Header.Get("CE-EventTypeVersion"),
-		CloudEventsVersion: req.Header.Get("CE-CloudEventsVersion"),
-		Source:             req.Header.Get("CE-Source"),
-		EventID:            req.Header.Get("CE-EventID"),
-		SchemaURL:          req.Header.Get("CE-SchemaURL"),
-		ContentType:        req.Header.Get("Content-Type"),
-	}
-	ts := req.Header.Get("CE-EventTime")
-	var err error
-	if ts != "" {
-		cex.EventTime, err = time.Parse(time.RFC3339, req.Header.Get("CE-EventTime"))
-		if err != nil {
-			return nil, nil, err
-		}
-	}
-	// TODO: handle extensions
-
-	ctx := context.WithValue(req.Context(), contextKey, cex)
-	in, err := ioutil.ReadAll(req.Body)
-	if err != nil {
-		return nil, nil, err
+// outgoingCloudEvent returns the CloudEventContext to use for the response:
+// the one set via SetCloudEvent, or the incoming event's context otherwise.
+func outgoingCloudEvent(ctx context.Context) CloudEventContext {
+	if out, ok := ctx.Value(outgoingKey).(*outgoingEvent); ok {
+		return out.cex
 	}
-	return in, ctx, nil
+	cex, _ := CloudEvent(ctx)
+	return cex
 }
 
-// Convert a function to an HTTP Handler
-func wrap(function interface{}) (http.Handler, error) {
+// wrap inspects function's signature to build the functionHandler that will
+// dispatch decoded CloudEvents to it.
+func wrap(function interface{}) (functionHandler, error) {
 	h := functionHandler{
 		f: reflect.ValueOf(function),
 	}
@@ -141,11 +88,11 @@ func wrap(function interface{}) (http.Handler, error) {
 	errType := reflect.TypeOf((*error)(nil)).Elem()
 	t := reflect.TypeOf(function)
 	if t.Kind() != reflect.Func {
-		return nil, fmt.Errorf("%v is not a function", t)
+		return h, fmt.Errorf("%v is not a function", t)
 	}
 
 	if t.NumIn() > 2 {
-		return nil, fmt.Errorf("%v takes too many arguments", t)
+		return h, fmt.Errorf("%v takes too many arguments", t)
 	}
 	if t.NumIn() > 0 {
 		if t.In(0).Implements(ctxType) {
@@ -156,13 +103,13 @@ func wrap(function interface{}) (http.Handler, error) {
 	}
 	if t.NumIn() == 2 {
 		if !h.needsContext {
-			return nil, fmt.Errorf("First argument must be of type context.Context: %v", t)
+			return h, fmt.Errorf("First argument must be of type context.Context: %v", t)
 		}
 		h.inType = t.In(1)
 	}
 
 	if t.NumOut() > 2 {
-		return nil, fmt.Errorf("%v returns too many outputs", t)
+		return h, fmt.Errorf("%v returns too many outputs", t)
 	}
 	if t.NumOut() > 0 {
 		if t.Out(0).Implements(errType) {
@@ -173,7 +120,7 @@ func wrap(function interface{}) (http.Handler, error) {
 	}
 	if t.NumOut() == 2 {
 		if h.hasError || !t.Out(1).Implements(errType) {
-			return nil, fmt.Errorf("Must return (data, error) with two arguments: %v", t)
+			return h, fmt.Errorf("Must return (data, error) with two arguments: %v", t)
 		}
 		h.hasError = true
 	}
@@ -0,0 +1,273 @@
+package efgh
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Message is a transport-agnostic CloudEvent: either an inbound event
+// returned by Protocol.Receive, or the function's response passed to
+// Protocol.Respond.
+type Message struct {
+	Context    CloudEventContext
+	Data       []byte
+	Structured bool
+
+	// raw carries transport-specific state a Protocol needs to correlate a
+	// Respond call with the Receive call that produced the Message (e.g.
+	// the HTTP response writer to reply to). It is nil for protocols with
+	// no response channel.
+	raw interface{}
+}
+
+// Protocol abstracts the transport a function is served behind, so the same
+// CloudEvents decoding and function-dispatch logic in Start can run over
+// HTTP, NATS, Kafka, or Google Pub/Sub without the user function changing.
+type Protocol interface {
+	// Receive blocks until the next inbound CloudEvent is available, or ctx
+	// is cancelled.
+	Receive(ctx context.Context) (Message, error)
+	// Respond delivers a function's response for a Message previously
+	// returned by Receive; several Messages may be awaiting a response at
+	// once, each correlated by its own transport state. Protocols with no notion of a response (e.g.
+	// fire-and-forget pub/sub) may treat this as a no-op.
+	Respond(ctx context.Context, msg Message) error
+}
+
+// decodeError marks a Receive failure as specific to the one inbound message
+// that caused it (e.g. malformed JSON, a missing required header, an
+// unparseable timestamp), as opposed to a transport/connection-level failure.
+// serve logs and skips past a decodeError instead of ending the dispatch
+// loop over it.
+type decodeError struct{ err error }
+
+func (e *decodeError) Error() string { return e.err.Error() }
+func (e *decodeError) Unwrap() error { return e.err }
+
+// newDecodeError wraps err as a decodeError, for a Protocol's Receive to
+// return when it fails to decode one particular message but should otherwise
+// keep serving.
+func newDecodeError(err error) error { return &decodeError{err: err} }
+
+// isDecodeError reports whether err (or an error it wraps) was produced by
+// newDecodeError.
+func isDecodeError(err error) bool {
+	var de *decodeError
+	return errors.As(err, &de)
+}
+
+// errorResponder is implemented by Protocols that can report a function
+// invocation error back to the caller (e.g. HTTP's 500 status), as opposed
+// to protocols where a failed invocation is only logged.
+type errorResponder interface {
+	RespondError(ctx context.Context, msg Message, err error) error
+}
+
+// dropResponder is implemented by Protocols that can acknowledge a message
+// was deliberately not processed because it didn't match a WithFilter
+// expression (e.g. HTTP's 204 No Content), as opposed to protocols where a
+// dropped message is simply never responded to.
+type dropResponder interface {
+	RespondDropped(ctx context.Context, msg Message) error
+}
+
+// defaultConcurrency is the number of Messages serve will invoke the
+// function for concurrently when Start isn't given WithConcurrency.
+const defaultConcurrency = 64
+
+// config holds the options accumulated from the Option values passed to
+// Start.
+type config struct {
+	protocol       Protocol
+	filterExpr     string
+	tracerProvider trace.TracerProvider
+	concurrency    int
+}
+
+// Option configures Start.
+type Option func(*config)
+
+// WithProtocol overrides the transport Protocol that Start serves the
+// function behind. Without this option, Start selects HTTP, unless the
+// EFGH_PROTOCOL environment variable names another supported protocol (see
+// protocolFromEnv).
+func WithProtocol(p Protocol) Option {
+	return func(c *config) { c.protocol = p }
+}
+
+// WithConcurrency bounds the number of Messages Start will invoke the
+// function for at once, so one slow or blocking invocation doesn't stall
+// unrelated in-flight messages but a burst of traffic also can't spawn an
+// unbounded number of goroutines. Defaults to defaultConcurrency.
+func WithConcurrency(n int) Option {
+	return func(c *config) { c.concurrency = n }
+}
+
+// Start instantiates a webserver to surface the supplied function
+// as an HTTP endpoint implementing the
+// [Cloud Events HTTP transport](https://github.com/cloudevents/spec/blob/v0.1/http-transport-binding.md#31-binary-content-mode),
+// or behind another transport Protocol selected with WithProtocol or the
+// EFGH_PROTOCOL environment variable.
+//
+// Start takes a flexible range of function signatures; any combination
+// of the following should work for input args:
+//
+// ```
+// func DoIt()
+// func DoIt(context.Context)
+// func DoIt([]bytes)
+// func DoIt(interface{})  // For JSON unmarshal
+// func DoIt(context.Context, [] bytes)
+// func DoIt(context.Context, interface{})  // For JSON unmarshal
+// ```
+//
+// Similarly, the return value of the function may be any of:
+//
+// ```
+// func DoIt()
+// func DoIt() error
+// func DoIt() []byte
+// func DoIt() interface{}  // For JSON marshall
+// func DoIt() ([]byte, error)
+// func DoIt() (interface{}, error)  // For JSON marshall
+// ```
+func Start(function interface{}, opts ...Option) {
+	handler, err := wrap(function)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.protocol == nil {
+		cfg.protocol = protocolFromEnv()
+	}
+	if cfg.concurrency <= 0 {
+		cfg.concurrency = defaultConcurrency
+	}
+
+	var filter *Filter
+	if cfg.filterExpr != "" {
+		f, err := ParseFilter(cfg.filterExpr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		filter = f
+	}
+
+	tp := cfg.tracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	tracer := tp.Tracer(tracerName)
+
+	if http, ok := cfg.protocol.(*httpProtocol); ok {
+		log.Fatal(http.listenAndServe(handler, filter, tracer, cfg.concurrency))
+		return
+	}
+	log.Fatal(serve(context.Background(), cfg.protocol, handler, filter, tracer, cfg.concurrency))
+}
+
+// protocolFromEnv selects the default Protocol for Start from the
+// EFGH_PROTOCOL environment variable ("http", the default; "nats"; "kafka";
+// or "pubsub"), reading each protocol's connection details from the
+// environment variables documented on its constructor.
+func protocolFromEnv() Protocol {
+	switch os.Getenv("EFGH_PROTOCOL") {
+	case "nats":
+		return NATS(os.Getenv("EFGH_NATS_URL"), os.Getenv("EFGH_NATS_SUBJECT"))
+	case "kafka":
+		return Kafka([]string{os.Getenv("EFGH_KAFKA_BROKER")}, os.Getenv("EFGH_KAFKA_TOPIC"))
+	case "pubsub":
+		return PubSub(os.Getenv("EFGH_PUBSUB_PROJECT"), os.Getenv("EFGH_PUBSUB_SUBSCRIPTION"))
+	default:
+		return HTTP()
+	}
+}
+
+// serve runs fh behind p, invoking it for every Message p.Receive returns
+// that matches filter (if non-nil), until Receive returns a non-nil error
+// that isn't a decodeError (including ctx cancellation); a decodeError is
+// logged and skipped, since it reflects one malformed inbound message rather
+// than a transport/connection failure. Each invocation runs in its own
+// goroutine, bounded to at most concurrency at a time, so a slow or blocking
+// invocation only stalls messages once that bound is saturated rather than
+// every other in-flight message.
+func serve(ctx context.Context, p Protocol, fh functionHandler, filter *Filter, tracer trace.Tracer, concurrency int) error {
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		msg, err := p.Receive(ctx)
+		if err != nil {
+			if isDecodeError(err) {
+				log.Printf("error decoding message: %v\n", err)
+				continue
+			}
+			return err
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(msg Message) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			dispatch(ctx, p, fh, filter, tracer, msg)
+		}(msg)
+	}
+}
+
+// dispatch filter-checks, invokes, and responds to a single Message received
+// from p. It is the body serve runs per-message, each on its own goroutine.
+func dispatch(ctx context.Context, p Protocol, fh functionHandler, filter *Filter, tracer trace.Tracer, msg Message) {
+	if filter != nil && !filter.Match(msg.Context) {
+		if dr, ok := p.(dropResponder); ok {
+			if err := dr.RespondDropped(ctx, msg); err != nil {
+				log.Printf("error responding to dropped message: %v\n", err)
+			}
+		}
+		return
+	}
+
+	spanCtx := extractTraceContext(ctx, msg.Context)
+	spanName := msg.Context.EventType
+	if spanName == "" {
+		spanName = "efgh.receive"
+	}
+	spanCtx, span := tracer.Start(spanCtx, spanName, trace.WithSpanKind(trace.SpanKindServer))
+	span.SetAttributes(
+		attribute.String("cloudevents.event_id", msg.Context.EventID),
+		attribute.String("cloudevents.event_type", msg.Context.EventType),
+		attribute.String("cloudevents.event_source", msg.Context.Source),
+	)
+
+	eventCtx := newEventContext(spanCtx, msg.Context)
+	out, err := fh.Invoke(eventCtx, msg.Data)
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		log.Printf("function error: %v\n", err)
+		if er, ok := p.(errorResponder); ok {
+			if respErr := er.RespondError(ctx, msg, err); respErr != nil {
+				log.Printf("error responding with function error: %v\n", respErr)
+			}
+		}
+		return
+	}
+	span.End()
+
+	resp := Message{Context: outgoingCloudEvent(eventCtx), Data: out, Structured: msg.Structured, raw: msg.raw}
+	if err := p.Respond(ctx, resp); err != nil {
+		log.Printf("error responding: %v\n", err)
+	}
+}
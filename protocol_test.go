@@ -0,0 +1,140 @@
+package efgh
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeReceive is one scripted (Message, error) pair a fakeProtocol's Receive
+// returns in order.
+type fakeReceive struct {
+	msg Message
+	err error
+}
+
+// fakeProtocol is a Protocol whose Receive replays a scripted sequence of
+// results, for exercising serve's dispatch loop without a real transport.
+type fakeProtocol struct {
+	recvs []fakeReceive
+
+	mu        sync.Mutex
+	idx       int
+	responded []Message
+	errored   []error
+	dropped   []Message
+}
+
+func (p *fakeProtocol) Receive(ctx context.Context) (Message, error) {
+	p.mu.Lock()
+	if p.idx >= len(p.recvs) {
+		p.mu.Unlock()
+		<-ctx.Done()
+		return Message{}, ctx.Err()
+	}
+	r := p.recvs[p.idx]
+	p.idx++
+	p.mu.Unlock()
+	return r.msg, r.err
+}
+
+func (p *fakeProtocol) Respond(ctx context.Context, msg Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.responded = append(p.responded, msg)
+	return nil
+}
+
+func (p *fakeProtocol) RespondError(ctx context.Context, msg Message, err error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.errored = append(p.errored, err)
+	return nil
+}
+
+func (p *fakeProtocol) RespondDropped(ctx context.Context, msg Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dropped = append(p.dropped, msg)
+	return nil
+}
+
+func echoHandler(t *testing.T) functionHandler {
+	t.Helper()
+	fh, err := wrap(func(data []byte) ([]byte, error) {
+		if string(data) == "fail" {
+			return nil, errors.New("function boom")
+		}
+		return data, nil
+	})
+	if err != nil {
+		t.Fatalf("wrap: %v", err)
+	}
+	return fh
+}
+
+func TestServeDispatch(t *testing.T) {
+	errStop := errors.New("transport closed")
+
+	p := &fakeProtocol{
+		recvs: []fakeReceive{
+			{err: newDecodeError(errors.New("malformed body"))},
+			{msg: Message{Context: CloudEventContext{EventType: "ignored"}, Data: []byte("drop me")}},
+			{msg: Message{Context: CloudEventContext{EventType: "create"}, Data: []byte("fail")}},
+			{msg: Message{Context: CloudEventContext{EventType: "create"}, Data: []byte("hello")}},
+			{err: errStop},
+		},
+	}
+
+	filter, err := ParseFilter(`type = 'create'`)
+	if err != nil {
+		t.Fatalf("ParseFilter: %v", err)
+	}
+	tracer := trace.NewNoopTracerProvider().Tracer("test")
+
+	err = serve(context.Background(), p, echoHandler(t), filter, tracer, 4)
+	if !errors.Is(err, errStop) {
+		t.Fatalf("serve returned %v, want %v", err, errStop)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.dropped) != 1 {
+		t.Errorf("dropped = %d messages, want 1 (decode error should have been skipped, not treated as a message)", len(p.dropped))
+	}
+	if len(p.errored) != 1 {
+		t.Errorf("errored = %d messages, want 1", len(p.errored))
+	}
+	if len(p.responded) != 1 {
+		t.Errorf("responded = %d messages, want 1", len(p.responded))
+	} else if string(p.responded[0].Data) != "hello" {
+		t.Errorf("responded data = %q, want %q", p.responded[0].Data, "hello")
+	}
+}
+
+func TestServeDecodeErrorDoesNotEndLoop(t *testing.T) {
+	errStop := errors.New("transport closed")
+	p := &fakeProtocol{
+		recvs: []fakeReceive{
+			{err: newDecodeError(errors.New("bad message 1"))},
+			{err: newDecodeError(errors.New("bad message 2"))},
+			{msg: Message{Context: CloudEventContext{EventType: "create"}, Data: []byte("hello")}},
+			{err: errStop},
+		},
+	}
+
+	tracer := trace.NewNoopTracerProvider().Tracer("test")
+	err := serve(context.Background(), p, echoHandler(t), nil, tracer, 4)
+	if !errors.Is(err, errStop) {
+		t.Fatalf("serve returned %v, want %v", err, errStop)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.responded) != 1 {
+		t.Errorf("responded = %d messages, want 1 (valid message after decode errors should still be processed)", len(p.responded))
+	}
+}
@@ -0,0 +1,82 @@
+package efgh
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies efgh's own spans in OpenTelemetry, per the
+// convention that a Tracer's name is the instrumented library.
+const tracerName = "github.com/evankanderson/efgh"
+
+// traceContextPropagator implements the CloudEvents distributed tracing
+// extension (https://github.com/cloudevents/spec/blob/v1.0.1/cloudevents/extensions/distributed-tracing.md),
+// which carries the same traceparent/tracestate values as the W3C Trace
+// Context HTTP headers, just as CloudEvents extension attributes instead.
+var traceContextPropagator = propagation.TraceContext{}
+
+// WithTracerProvider overrides the OpenTelemetry TracerProvider Start uses
+// to create a server span for each received event, parented from the
+// traceparent/tracestate extension attributes carried on the event, if any.
+// Without this option, Start uses otel.GetTracerProvider().
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) { c.tracerProvider = tp }
+}
+
+// extractTraceContext returns ctx augmented with the remote span context
+// described by cex's "traceparent"/"tracestate" extension attributes, or ctx
+// unchanged if neither is present.
+func extractTraceContext(ctx context.Context, cex CloudEventContext) context.Context {
+	carrier := propagation.MapCarrier{}
+	if tp, ok := traceExtension(cex, "traceparent"); ok {
+		carrier["traceparent"] = tp
+	}
+	if ts, ok := traceExtension(cex, "tracestate"); ok {
+		carrier["tracestate"] = ts
+	}
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return traceContextPropagator.Extract(ctx, carrier)
+}
+
+// injectTraceContext returns a copy of cex with the span carried by ctx, if
+// any, encoded into its "traceparent"/"tracestate" extension attributes, for
+// propagation to whatever (*Client).Send delivers the event to.
+func injectTraceContext(ctx context.Context, cex CloudEventContext) CloudEventContext {
+	carrier := propagation.MapCarrier{}
+	traceContextPropagator.Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return cex
+	}
+
+	ext := make(map[string]json.RawMessage, len(cex.Extensions)+len(carrier))
+	for k, v := range cex.Extensions {
+		ext[k] = v
+	}
+	for k, v := range carrier {
+		b, err := json.Marshal(v)
+		if err != nil {
+			continue
+		}
+		ext[k] = b
+	}
+	cex.Extensions = ext
+	return cex
+}
+
+// traceExtension reads a string-valued extension attribute from cex.
+func traceExtension(cex CloudEventContext, name string) (string, bool) {
+	raw, ok := cex.Extensions[name]
+	if !ok {
+		return "", false
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", false
+	}
+	return s, true
+}
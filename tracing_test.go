@@ -0,0 +1,129 @@
+package efgh
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestExtractTraceContext(t *testing.T) {
+	cex := CloudEventContext{
+		Extensions: map[string]json.RawMessage{
+			"traceparent": json.RawMessage(`"` + testTraceparent + `"`),
+			"tracestate":  json.RawMessage(`"vendor=val"`),
+		},
+	}
+
+	ctx := extractTraceContext(context.Background(), cex)
+	sc := trace.SpanContextFromContext(ctx)
+
+	if got, want := sc.TraceID().String(), "4bf92f3577b34da6a3ce929d0e0e4736"; got != want {
+		t.Errorf("TraceID = %q, want %q", got, want)
+	}
+	if got, want := sc.SpanID().String(), "00f067aa0ba902b7"; got != want {
+		t.Errorf("SpanID = %q, want %q", got, want)
+	}
+	if !sc.IsRemote() {
+		t.Error("expected a remote span context")
+	}
+	if got, want := sc.TraceState().String(), "vendor=val"; got != want {
+		t.Errorf("TraceState = %q, want %q", got, want)
+	}
+}
+
+func TestExtractTraceContextNoExtensions(t *testing.T) {
+	ctx := extractTraceContext(context.Background(), CloudEventContext{})
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		t.Error("expected no span context without traceparent/tracestate extensions")
+	}
+}
+
+func TestInjectTraceContext(t *testing.T) {
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatalf("TraceIDFromHex: %v", err)
+	}
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	if err != nil {
+		t.Fatalf("SpanIDFromHex: %v", err)
+	}
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	cex := injectTraceContext(ctx, CloudEventContext{Extensions: map[string]json.RawMessage{
+		"customattr": json.RawMessage(`"hello"`),
+	}})
+
+	traceparent, ok := traceExtension(cex, "traceparent")
+	if !ok {
+		t.Fatal("expected traceparent extension to be populated")
+	}
+	if traceparent != testTraceparent {
+		t.Errorf("traceparent = %q, want %q", traceparent, testTraceparent)
+	}
+
+	if custom, ok := traceExtension(cex, "customattr"); !ok || custom != "hello" {
+		t.Errorf("customattr = %q, ok %v, want %q, true", custom, ok, "hello")
+	}
+}
+
+// TestSendInjectsTraceContext confirms (*Client).Send carries
+// injectTraceContext's output onto the wire, not just CloudEventContext
+// values already set on the Event.
+func TestSendInjectsTraceContext(t *testing.T) {
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatalf("TraceIDFromHex: %v", err)
+	}
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	if err != nil {
+		t.Fatalf("SpanIDFromHex: %v", err)
+	}
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	var gotHeaders http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		gotHeaders = req.Header.Clone()
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	event, err := c.NewEvent("com.example.created", []byte("x"))
+	if err != nil {
+		t.Fatalf("NewEvent: %v", err)
+	}
+
+	if err := c.Send(ctx, event); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	cex, err := decodeBinaryMessage(httpHeaderMap(gotHeaders))
+	if err != nil {
+		t.Fatalf("decodeBinaryMessage: %v", err)
+	}
+	traceparent, ok := traceExtension(cex, "traceparent")
+	if !ok {
+		t.Fatal("expected traceparent extension on the request headers")
+	}
+	if traceparent != testTraceparent {
+		t.Errorf("traceparent = %q, want %q", traceparent, testTraceparent)
+	}
+}
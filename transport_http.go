@@ -0,0 +1,210 @@
+package efgh
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// httpProtocol implements Protocol on top of net/http: each inbound POST is
+// decoded into a Message and handed to Receive, with the function's eventual
+// response (or error) written back to that request's http.ResponseWriter by
+// Respond / RespondError.
+type httpProtocol struct {
+	exchanges chan httpExchange
+}
+
+// httpExchange pairs a decoded Message with the channel its handler result
+// should be delivered on.
+type httpExchange struct {
+	msg  Message
+	done chan httpResult
+}
+
+// httpResult is what Respond/RespondError/RespondDropped deliver back to
+// ServeHTTP.
+type httpResult struct {
+	msg     Message
+	err     error
+	dropped bool
+}
+
+// HTTP returns a Protocol that serves the function over HTTP, implementing
+// the CloudEvents HTTP transport binding in Binary and Structured Content
+// Mode. This is Start's default Protocol.
+func HTTP() Protocol {
+	return &httpProtocol{exchanges: make(chan httpExchange)}
+}
+
+func (p *httpProtocol) Receive(ctx context.Context) (Message, error) {
+	select {
+	case ex := <-p.exchanges:
+		msg := ex.msg
+		msg.raw = ex.done
+		return msg, nil
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	}
+}
+
+func (p *httpProtocol) Respond(ctx context.Context, msg Message) error {
+	done, ok := msg.raw.(chan httpResult)
+	if !ok {
+		return fmt.Errorf("efgh: message was not received over HTTP")
+	}
+	done <- httpResult{msg: msg}
+	return nil
+}
+
+func (p *httpProtocol) RespondError(ctx context.Context, msg Message, err error) error {
+	done, ok := msg.raw.(chan httpResult)
+	if !ok {
+		return fmt.Errorf("efgh: message was not received over HTTP")
+	}
+	done <- httpResult{err: err}
+	return nil
+}
+
+// RespondDropped acknowledges a message that a WithFilter expression
+// evaluated false for, so ServeHTTP can reply 204 No Content rather than
+// leaving the request hanging.
+func (p *httpProtocol) RespondDropped(ctx context.Context, msg Message) error {
+	done, ok := msg.raw.(chan httpResult)
+	if !ok {
+		return fmt.Errorf("efgh: message was not received over HTTP")
+	}
+	done <- httpResult{dropped: true}
+	return nil
+}
+
+func (p *httpProtocol) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	msg, err := decodeHTTPRequest(req)
+	if err != nil {
+		rw.Header().Set("Content-Type", "text/plain")
+		rw.WriteHeader(http.StatusExpectationFailed)
+		io.WriteString(rw, err.Error())
+		return
+	}
+
+	done := make(chan httpResult, 1)
+	p.exchanges <- httpExchange{msg: msg, done: done}
+	result := <-done
+
+	if result.dropped {
+		rw.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if result.err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(rw, result.err.Error())
+		return
+	}
+	if strings.Contains(req.Header.Get("Accept"), "application/cloudevents+json") {
+		writeStructuredHTTP(rw, result.msg)
+		return
+	}
+	writeBinaryHTTP(rw, result.msg)
+}
+
+// listenAndServe starts serving fh over HTTP: the generic dispatch loop (see
+// serve) runs in the background, fed by ServeHTTP through p.exchanges, and
+// invokes fh for up to concurrency requests at once.
+func (p *httpProtocol) listenAndServe(fh functionHandler, filter *Filter, tracer trace.Tracer, concurrency int) error {
+	go func() {
+		if err := serve(context.Background(), p, fh, filter, tracer, concurrency); err != nil {
+			log.Fatal(err)
+		}
+	}()
+	http.Handle("/", p)
+	port := ":" + os.Getenv("PORT")
+	log.Printf("Listening on %s\n", port)
+	return http.ListenAndServe(port, nil)
+}
+
+// decodeHTTPRequest decodes an inbound HTTP request into a Message,
+// negotiating Binary vs Structured Content Mode and CloudEvents version.
+func decodeHTTPRequest(req *http.Request) (Message, error) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return Message{}, err
+	}
+
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/cloudevents") {
+		cex, data, err := decodeStructuredMessage(body)
+		if err != nil {
+			return Message{}, err
+		}
+		return Message{Context: cex, Data: data, Structured: true}, nil
+	}
+
+	cex, err := decodeBinaryMessage(httpHeaderMap(req.Header))
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Context: cex, Data: body}, nil
+}
+
+// writeStructuredHTTP writes msg as a Structured Content Mode CloudEvents
+// envelope, in the CloudEvents version carried by msg.Context, defaulting to
+// v1.0.
+func writeStructuredHTTP(rw http.ResponseWriter, msg Message) {
+	version := msg.Context.CloudEventsVersion
+	if version == "" {
+		version = v1
+	}
+	body, err := encodeStructuredMessage(msg.Context, msg.Data, version)
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(rw, err.Error())
+		return
+	}
+	rw.Header().Set("Content-Type", "application/cloudevents+json")
+	rw.WriteHeader(http.StatusOK)
+	rw.Write(body)
+}
+
+// writeBinaryHTTP writes msg as a Binary Content Mode response: msg.Data as
+// the body, with msg.Context carried on ce-* headers (and Content-Type, if
+// set), in the CloudEvents version carried by msg.Context, defaulting to
+// v1.0. This mirrors the binary-mode Respond behavior of the NATS, Kafka,
+// and Pub/Sub Protocols, so SetCloudEvent and extensions set by the function
+// are not silently dropped when the caller didn't request Structured
+// Content Mode.
+func writeBinaryHTTP(rw http.ResponseWriter, msg Message) {
+	version := msg.Context.CloudEventsVersion
+	if version == "" {
+		version = v1
+	}
+	for k, v := range encodeBinaryHeaders(msg.Context, version) {
+		rw.Header().Set(k, v)
+	}
+	if msg.Context.ContentType != "" {
+		rw.Header().Set("Content-Type", msg.Context.ContentType)
+	}
+	rw.WriteHeader(http.StatusOK)
+	rw.Write(msg.Data)
+}
+
+// httpHeaderMap flattens an http.Header into the lowercased
+// map[string]string shape the version-agnostic codecs expect.
+func httpHeaderMap(h http.Header) map[string]string {
+	m := make(map[string]string, len(h))
+	for k, v := range h {
+		if len(v) > 0 {
+			m[strings.ToLower(k)] = v[0]
+		}
+	}
+	return m
+}
@@ -0,0 +1,114 @@
+package efgh
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServeHTTPStructuredResponseOverride(t *testing.T) {
+	incoming := CloudEventContext{
+		EventID:            "1234",
+		Source:             "/widgets",
+		EventType:          "com.example.created",
+		CloudEventsVersion: v1,
+	}
+	reqBody, err := encodeStructuredMessage(incoming, []byte(`{"hello":"world"}`), v1)
+	if err != nil {
+		t.Fatalf("encodeStructuredMessage: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/", bytes.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/cloudevents+json")
+	req.Header.Set("Accept", "application/cloudevents+json")
+	rw := httptest.NewRecorder()
+
+	p := HTTP().(*httpProtocol)
+	served := make(chan struct{})
+	go func() {
+		p.ServeHTTP(rw, req)
+		close(served)
+	}()
+
+	msg, err := p.Receive(context.Background())
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if !msg.Structured {
+		t.Error("expected a Structured Content Mode Message from a structured-mode request")
+	}
+	if msg.Context.EventID != incoming.EventID {
+		t.Errorf("decoded EventID = %q, want %q", msg.Context.EventID, incoming.EventID)
+	}
+
+	eventCtx := newEventContext(context.Background(), msg.Context)
+	override := CloudEventContext{
+		EventID:            "override-id",
+		Source:             "/overridden",
+		EventType:          "com.example.overridden",
+		CloudEventsVersion: v1,
+	}
+	SetCloudEvent(eventCtx, override)
+
+	resp := Message{Context: outgoingCloudEvent(eventCtx), Data: []byte(`{"out":"data"}`), Structured: msg.Structured, raw: msg.raw}
+	if err := p.Respond(context.Background(), resp); err != nil {
+		t.Fatalf("Respond: %v", err)
+	}
+	<-served
+
+	if ct := rw.Header().Get("Content-Type"); ct != "application/cloudevents+json" {
+		t.Errorf("Content-Type = %q, want %q", ct, "application/cloudevents+json")
+	}
+	gotCex, gotData, err := decodeStructuredMessage(rw.Body.Bytes())
+	if err != nil {
+		t.Fatalf("decodeStructuredMessage: %v", err)
+	}
+	if gotCex.EventID != override.EventID || gotCex.Source != override.Source || gotCex.EventType != override.EventType {
+		t.Errorf("response context = %+v, want the SetCloudEvent override %+v", gotCex, override)
+	}
+	if string(gotData) != `{"out":"data"}` {
+		t.Errorf("response data = %s, want %s", gotData, `{"out":"data"}`)
+	}
+}
+
+func TestServeHTTPBinaryResponseCarriesContext(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", bytes.NewReader([]byte(`{"hello":"world"}`)))
+	req.Header.Set("ce-id", "1234")
+	req.Header.Set("ce-source", "/widgets")
+	req.Header.Set("ce-type", "com.example.created")
+	req.Header.Set("ce-specversion", v1)
+	rw := httptest.NewRecorder()
+
+	p := HTTP().(*httpProtocol)
+	served := make(chan struct{})
+	go func() {
+		p.ServeHTTP(rw, req)
+		close(served)
+	}()
+
+	msg, err := p.Receive(context.Background())
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if msg.Structured {
+		t.Error("expected a Binary Content Mode Message from a binary-mode request")
+	}
+
+	resp := Message{Context: msg.Context, Data: []byte("response body"), raw: msg.raw}
+	if err := p.Respond(context.Background(), resp); err != nil {
+		t.Fatalf("Respond: %v", err)
+	}
+	<-served
+
+	cex, err := decodeBinaryMessage(httpHeaderMap(rw.Header()))
+	if err != nil {
+		t.Fatalf("decodeBinaryMessage: %v", err)
+	}
+	if cex.EventID != msg.Context.EventID || cex.Source != msg.Context.Source {
+		t.Errorf("response headers decoded to = %+v, want core fields of %+v", cex, msg.Context)
+	}
+	if rw.Body.String() != "response body" {
+		t.Errorf("response body = %q, want %q", rw.Body.String(), "response body")
+	}
+}
@@ -0,0 +1,84 @@
+package efgh
+
+import (
+	"context"
+	"strings"
+
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// kafkaProtocol implements Protocol over a Kafka topic: each message is
+// decoded as Binary or Structured Content Mode CloudEvents, negotiated from
+// its "content-type" record header (Kafka record headers standing in for
+// HTTP headers), and Respond publishes the function's output, in the same
+// content mode, to a "<topic>-reply" topic.
+type kafkaProtocol struct {
+	reader *kafka.Reader
+	writer *kafka.Writer
+}
+
+// Kafka returns a Protocol that receives CloudEvents from topic on brokers
+// and responds on "<topic>-reply". Both are also settable via the
+// EFGH_KAFKA_BROKER and EFGH_KAFKA_TOPIC environment variables when selected
+// through Start.
+func Kafka(brokers []string, topic string) Protocol {
+	return &kafkaProtocol{
+		reader: kafka.NewReader(kafka.ReaderConfig{Brokers: brokers, Topic: topic}),
+		writer: &kafka.Writer{Addr: kafka.TCP(brokers...), Topic: topic + "-reply"},
+	}
+}
+
+func (p *kafkaProtocol) Receive(ctx context.Context) (Message, error) {
+	m, err := p.reader.ReadMessage(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+
+	headers := kafkaHeaderMap(m.Headers)
+	if strings.HasPrefix(headers["content-type"], "application/cloudevents") {
+		cex, data, err := decodeStructuredMessage(m.Value)
+		if err != nil {
+			return Message{}, newDecodeError(err)
+		}
+		return Message{Context: cex, Data: data, Structured: true}, nil
+	}
+
+	cex, err := decodeBinaryMessage(headers)
+	if err != nil {
+		return Message{}, newDecodeError(err)
+	}
+	return Message{Context: cex, Data: m.Value}, nil
+}
+
+func (p *kafkaProtocol) Respond(ctx context.Context, msg Message) error {
+	version := msg.Context.CloudEventsVersion
+	if version == "" {
+		version = v1
+	}
+
+	if msg.Structured {
+		body, err := encodeStructuredMessage(msg.Context, msg.Data, version)
+		if err != nil {
+			return err
+		}
+		kHeaders := []kafka.Header{{Key: "content-type", Value: []byte("application/cloudevents+json")}}
+		return p.writer.WriteMessages(ctx, kafka.Message{Value: body, Headers: kHeaders})
+	}
+
+	headers := encodeBinaryHeaders(msg.Context, version)
+	kHeaders := make([]kafka.Header, 0, len(headers))
+	for k, v := range headers {
+		kHeaders = append(kHeaders, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	return p.writer.WriteMessages(ctx, kafka.Message{Value: msg.Data, Headers: kHeaders})
+}
+
+// kafkaHeaderMap flattens Kafka record headers into the lowercased
+// map[string]string shape the version-agnostic codecs expect.
+func kafkaHeaderMap(headers []kafka.Header) map[string]string {
+	m := make(map[string]string, len(headers))
+	for _, h := range headers {
+		m[strings.ToLower(h.Key)] = string(h.Value)
+	}
+	return m
+}
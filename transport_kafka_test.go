@@ -0,0 +1,35 @@
+package efgh
+
+import (
+	"testing"
+
+	kafka "github.com/segmentio/kafka-go"
+)
+
+func TestKafkaHeaderMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []kafka.Header
+		want    map[string]string
+	}{
+		{"empty", nil, map[string]string{}},
+		{"lowercases keys", []kafka.Header{{Key: "Content-Type", Value: []byte("application/json")}}, map[string]string{"content-type": "application/json"}},
+		{"last value wins for duplicate keys", []kafka.Header{
+			{Key: "ce-source", Value: []byte("/first")},
+			{Key: "Ce-Source", Value: []byte("/second")},
+		}, map[string]string{"ce-source": "/second"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := kafkaHeaderMap(tt.headers)
+			if len(got) != len(tt.want) {
+				t.Fatalf("kafkaHeaderMap(%v) = %v, want %v", tt.headers, got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("kafkaHeaderMap(%v)[%q] = %q, want %q", tt.headers, k, got[k], v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package efgh
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsProtocol implements Protocol over a NATS subject: each message
+// published to the subject is decoded as Binary or Structured Content Mode
+// CloudEvents, negotiated from its "content-type" header (NATS message
+// headers standing in for HTTP headers), and Respond publishes the
+// function's output, in the same content mode, to the triggering message's
+// reply subject, if any.
+type natsProtocol struct {
+	url, subject string
+
+	mu   sync.Mutex
+	conn *nats.Conn
+	sub  *nats.Subscription
+	msgs chan *nats.Msg
+}
+
+// NATS returns a Protocol that receives and responds to CloudEvents on a
+// NATS subject. It lazily dials url (also settable via the EFGH_NATS_URL
+// environment variable when selected through Start) and subscribes to
+// subject (EFGH_NATS_SUBJECT) on the first call to Receive.
+func NATS(url, subject string) Protocol {
+	return &natsProtocol{url: url, subject: subject}
+}
+
+// connect lazily dials and subscribes, since Protocol has no separate
+// initialization hook.
+func (p *natsProtocol) connect() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn != nil {
+		return nil
+	}
+
+	conn, err := nats.Connect(p.url)
+	if err != nil {
+		return err
+	}
+	msgs := make(chan *nats.Msg, 64)
+	sub, err := conn.ChanSubscribe(p.subject, msgs)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	p.conn, p.sub, p.msgs = conn, sub, msgs
+	return nil
+}
+
+func (p *natsProtocol) Receive(ctx context.Context) (Message, error) {
+	if err := p.connect(); err != nil {
+		return Message{}, err
+	}
+
+	select {
+	case m := <-p.msgs:
+		headers := natsHeaderMap(m.Header)
+		if strings.HasPrefix(headers["content-type"], "application/cloudevents") {
+			cex, data, err := decodeStructuredMessage(m.Data)
+			if err != nil {
+				return Message{}, newDecodeError(err)
+			}
+			return Message{Context: cex, Data: data, Structured: true, raw: m}, nil
+		}
+
+		cex, err := decodeBinaryMessage(headers)
+		if err != nil {
+			return Message{}, newDecodeError(err)
+		}
+		return Message{Context: cex, Data: m.Data, raw: m}, nil
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	}
+}
+
+func (p *natsProtocol) Respond(ctx context.Context, msg Message) error {
+	m, ok := msg.raw.(*nats.Msg)
+	if !ok || m.Reply == "" {
+		return nil
+	}
+
+	version := msg.Context.CloudEventsVersion
+	if version == "" {
+		version = v1
+	}
+
+	if msg.Structured {
+		body, err := encodeStructuredMessage(msg.Context, msg.Data, version)
+		if err != nil {
+			return err
+		}
+		reply := &nats.Msg{Subject: m.Reply, Data: body, Header: nats.Header{"Content-Type": []string{"application/cloudevents+json"}}}
+		return p.conn.PublishMsg(reply)
+	}
+
+	reply := &nats.Msg{Subject: m.Reply, Data: msg.Data, Header: nats.Header{}}
+	for k, v := range encodeBinaryHeaders(msg.Context, version) {
+		reply.Header.Set(k, v)
+	}
+	return p.conn.PublishMsg(reply)
+}
+
+// natsHeaderMap flattens a nats.Header into the lowercased map[string]string
+// shape the version-agnostic codecs expect.
+func natsHeaderMap(h nats.Header) map[string]string {
+	m := make(map[string]string, len(h))
+	for k, v := range h {
+		if len(v) > 0 {
+			m[strings.ToLower(k)] = v[0]
+		}
+	}
+	return m
+}
@@ -0,0 +1,33 @@
+package efgh
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestNatsHeaderMap(t *testing.T) {
+	tests := []struct {
+		name string
+		h    nats.Header
+		want map[string]string
+	}{
+		{"empty", nats.Header{}, map[string]string{}},
+		{"lowercases keys", nats.Header{"Content-Type": []string{"application/json"}}, map[string]string{"content-type": "application/json"}},
+		{"takes first value", nats.Header{"Ce-Source": []string{"/widgets", "/ignored"}}, map[string]string{"ce-source": "/widgets"}},
+		{"drops keys with no values", nats.Header{"Ce-Empty": nil}, map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := natsHeaderMap(tt.h)
+			if len(got) != len(tt.want) {
+				t.Fatalf("natsHeaderMap(%v) = %v, want %v", tt.h, got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("natsHeaderMap(%v)[%q] = %q, want %q", tt.h, k, got[k], v)
+				}
+			}
+		})
+	}
+}
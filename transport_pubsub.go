@@ -0,0 +1,155 @@
+package efgh
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// pubsubProtocol implements Protocol over a Google Cloud Pub/Sub
+// subscription: each message is decoded as Binary or Structured Content Mode
+// CloudEvents, negotiated from its "content-type" attribute (Pub/Sub message
+// attributes standing in for HTTP headers), and acked once the function has
+// run successfully (nacked on failure, so Pub/Sub redelivers it). Pub/Sub
+// has no reply channel, so Respond publishes the function's output, in the
+// same content mode, to a "<subscription>-reply" topic if one exists, and
+// otherwise discards it.
+type pubsubProtocol struct {
+	projectID, subscriptionID string
+
+	mu     sync.Mutex
+	client *pubsub.Client
+	msgs   chan *pubsub.Message
+	reply  *pubsub.Topic
+}
+
+// PubSub returns a Protocol that receives CloudEvents from a Google Cloud
+// Pub/Sub subscription. projectID and subscriptionID are also settable via
+// the EFGH_PUBSUB_PROJECT and EFGH_PUBSUB_SUBSCRIPTION environment variables
+// when selected through Start.
+func PubSub(projectID, subscriptionID string) Protocol {
+	return &pubsubProtocol{projectID: projectID, subscriptionID: subscriptionID}
+}
+
+// connect lazily creates the Pub/Sub client and starts pulling messages from
+// the subscription, since Protocol has no separate initialization hook.
+func (p *pubsubProtocol) connect(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.client != nil {
+		return nil
+	}
+
+	client, err := pubsub.NewClient(ctx, p.projectID)
+	if err != nil {
+		return err
+	}
+	msgs := make(chan *pubsub.Message, 64)
+	sub := client.Subscription(p.subscriptionID)
+	go func() {
+		// Receive blocks until its context is cancelled; its error is
+		// discarded and msgs is left open, so Receive callers will then
+		// block on their own ctx instead of spinning on a dead
+		// subscription.
+		sub.Receive(context.Background(), func(_ context.Context, m *pubsub.Message) {
+			msgs <- m
+		})
+	}()
+
+	replyTopic := client.Topic(p.subscriptionID + "-reply")
+	if exists, err := replyTopic.Exists(ctx); err == nil && exists {
+		p.reply = replyTopic
+	}
+
+	p.client, p.msgs = client, msgs
+	return nil
+}
+
+func (p *pubsubProtocol) Receive(ctx context.Context) (Message, error) {
+	if err := p.connect(ctx); err != nil {
+		return Message{}, err
+	}
+
+	select {
+	case m := <-p.msgs:
+		attrs := pubsubAttrMap(m.Attributes)
+		if strings.HasPrefix(attrs["content-type"], "application/cloudevents") {
+			cex, data, err := decodeStructuredMessage(m.Data)
+			if err != nil {
+				m.Nack()
+				return Message{}, newDecodeError(err)
+			}
+			return Message{Context: cex, Data: data, Structured: true, raw: m}, nil
+		}
+
+		cex, err := decodeBinaryMessage(attrs)
+		if err != nil {
+			m.Nack()
+			return Message{}, newDecodeError(err)
+		}
+		return Message{Context: cex, Data: m.Data, raw: m}, nil
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	}
+}
+
+// Respond acks the message that produced msg, now that the function has run
+// successfully, and publishes its output to the reply topic if one exists.
+func (p *pubsubProtocol) Respond(ctx context.Context, msg Message) error {
+	if m, ok := msg.raw.(*pubsub.Message); ok {
+		m.Ack()
+	}
+	if p.reply == nil {
+		return nil
+	}
+
+	version := msg.Context.CloudEventsVersion
+	if version == "" {
+		version = v1
+	}
+
+	var pm *pubsub.Message
+	if msg.Structured {
+		body, err := encodeStructuredMessage(msg.Context, msg.Data, version)
+		if err != nil {
+			return err
+		}
+		pm = &pubsub.Message{Data: body, Attributes: map[string]string{"content-type": "application/cloudevents+json"}}
+	} else {
+		pm = &pubsub.Message{Data: msg.Data, Attributes: encodeBinaryHeaders(msg.Context, version)}
+	}
+	result := p.reply.Publish(ctx, pm)
+	_, err := result.Get(ctx)
+	return err
+}
+
+// RespondError nacks the message that produced msg so Pub/Sub redelivers it,
+// since the function failed to process it.
+func (p *pubsubProtocol) RespondError(ctx context.Context, msg Message, err error) error {
+	if m, ok := msg.raw.(*pubsub.Message); ok {
+		m.Nack()
+	}
+	return nil
+}
+
+// RespondDropped acks the message that produced msg: it was deliberately
+// skipped because it didn't match a WithFilter expression, not a failure, so
+// it should not be redelivered.
+func (p *pubsubProtocol) RespondDropped(ctx context.Context, msg Message) error {
+	if m, ok := msg.raw.(*pubsub.Message); ok {
+		m.Ack()
+	}
+	return nil
+}
+
+// pubsubAttrMap lowercases Pub/Sub message attribute keys into the shape the
+// version-agnostic codecs expect.
+func pubsubAttrMap(attrs map[string]string) map[string]string {
+	m := make(map[string]string, len(attrs))
+	for k, v := range attrs {
+		m[strings.ToLower(k)] = v
+	}
+	return m
+}
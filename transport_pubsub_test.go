@@ -0,0 +1,160 @@
+package efgh
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+)
+
+func TestPubsubAttrMap(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs map[string]string
+		want  map[string]string
+	}{
+		{"empty", map[string]string{}, map[string]string{}},
+		{"lowercases keys", map[string]string{"Content-Type": "application/json"}, map[string]string{"content-type": "application/json"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pubsubAttrMap(tt.attrs)
+			if len(got) != len(tt.want) {
+				t.Fatalf("pubsubAttrMap(%v) = %v, want %v", tt.attrs, got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("pubsubAttrMap(%v)[%q] = %q, want %q", tt.attrs, k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+// receivedPubsubMessage starts an in-process fake Pub/Sub service (pstest),
+// publishes one message to it, and pulls it back as a real, still-unacked
+// *pubsub.Message, so Respond/RespondError/RespondDropped can be exercised
+// against a genuine Ack/Nack without a live GCP project or subscription.
+func receivedPubsubMessage(t *testing.T, attrs map[string]string) (srv *pstest.Server, msg *pubsub.Message) {
+	t.Helper()
+	srv = pstest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+
+	conn, err := grpc.Dial(srv.Addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc.Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	client, err := pubsub.NewClient(ctx, "test-project", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("pubsub.NewClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	topic, err := client.CreateTopic(ctx, "test-topic")
+	if err != nil {
+		t.Fatalf("CreateTopic: %v", err)
+	}
+	sub, err := client.CreateSubscription(ctx, "test-sub", pubsub.SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	srv.Publish(topic.String(), []byte("payload"), attrs)
+
+	received := make(chan *pubsub.Message, 1)
+	go sub.Receive(ctx, func(_ context.Context, m *pubsub.Message) {
+		received <- m
+	})
+
+	select {
+	case msg = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the published message")
+	}
+	return srv, msg
+}
+
+// waitForAcks polls srv for up to a second until msgID has been acked at
+// least once, since acks are delivered to the fake server asynchronously
+// over its gRPC stream.
+func waitForAcks(t *testing.T, srv *pstest.Server, msgID string) int {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		if acks := srv.Message(msgID).Acks; acks > 0 || time.Now().After(deadline) {
+			return acks
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitForNack polls srv for up to two seconds until msgID has a zero-deadline
+// modack recorded, which is how the client library signals a Nack (as
+// opposed to the non-zero-deadline modacks sent to extend the lease on a
+// message still being processed).
+func waitForNack(t *testing.T, srv *pstest.Server, msgID string) bool {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		for _, m := range srv.Message(msgID).Modacks {
+			if m.AckDeadline == 0 {
+				return true
+			}
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPubsubRespondAcksMessage(t *testing.T) {
+	srv, msg := receivedPubsubMessage(t, nil)
+
+	p := &pubsubProtocol{}
+	resp := Message{Context: CloudEventContext{CloudEventsVersion: v1}, Data: []byte("out"), raw: msg}
+	if err := p.Respond(context.Background(), resp); err != nil {
+		t.Fatalf("Respond: %v", err)
+	}
+
+	if got := waitForAcks(t, srv, msg.ID); got != 1 {
+		t.Errorf("Acks = %d, want 1", got)
+	}
+}
+
+func TestPubsubRespondDroppedAcksMessage(t *testing.T) {
+	srv, msg := receivedPubsubMessage(t, nil)
+
+	p := &pubsubProtocol{}
+	if err := p.RespondDropped(context.Background(), Message{raw: msg}); err != nil {
+		t.Fatalf("RespondDropped: %v", err)
+	}
+
+	if got := waitForAcks(t, srv, msg.ID); got != 1 {
+		t.Errorf("Acks = %d, want 1, so a filtered-out message is not redelivered", got)
+	}
+}
+
+func TestPubsubRespondErrorNacksMessage(t *testing.T) {
+	srv, msg := receivedPubsubMessage(t, nil)
+
+	p := &pubsubProtocol{}
+	if err := p.RespondError(context.Background(), Message{raw: msg}, nil); err != nil {
+		t.Fatalf("RespondError: %v", err)
+	}
+
+	if !waitForNack(t, srv, msg.ID) {
+		t.Error("expected a zero-deadline modack (nack) so the message is redelivered promptly")
+	}
+	if got := srv.Message(msg.ID).Acks; got != 0 {
+		t.Errorf("Acks = %d, want 0: a failed message must not be acked, so Pub/Sub redelivers it", got)
+	}
+}